@@ -18,7 +18,7 @@ func main() {
 	}).
 		AddFilter(
 			SizeFilter{"Non compressed data on server"},
-			CompressFilter{},
+			rpc.NewErrorFilter(CompressFilter{}),
 			SizeFilter{"Compressed data on server"},
 		)
 	server.Debug = true
@@ -26,7 +26,7 @@ func main() {
 	client := rpc.NewClient(server.URI())
 	client.AddFilter(
 		SizeFilter{"Non compressed data on client"},
-		CompressFilter{},
+		rpc.NewErrorFilter(CompressFilter{}),
 		SizeFilter{"Compressed data on client"},
 	)
 	var testService *TestService
@@ -13,19 +13,25 @@ import (
 type CompressFilter struct{}
 
 // InputFilter ...
-func (CompressFilter) InputFilter(data []byte, context rpc.Context) []byte {
+func (CompressFilter) InputFilter(data []byte, context rpc.Context) ([]byte, error) {
 	b := io.NewByteReader(data)
-	reader, _ := gzip.NewReader(b)
+	reader, err := gzip.NewReader(b)
+	if err != nil {
+		return nil, err
+	}
 	defer reader.Close()
-	data, _ = ioutil.ReadAll(reader)
-	return data
+	return ioutil.ReadAll(reader)
 }
 
 // OutputFilter ...
-func (CompressFilter) OutputFilter(data []byte, context rpc.Context) []byte {
+func (CompressFilter) OutputFilter(data []byte, context rpc.Context) ([]byte, error) {
 	b := &io.ByteWriter{}
 	writer := gzip.NewWriter(b)
-	writer.Write(data)
-	writer.Flush()
-	return b.Bytes()
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
 }
@@ -82,14 +82,14 @@ func main() {
 			statFilter{"Server: BeforeFilter"}.handler,
 			sizeFilter{"Server: Compressed"}.handler,
 		).
-		AddFilter(CompressFilter{}).
+		AddFilter(rpc.NewErrorFilter(CompressFilter{})).
 		AddAfterFilterHandler(
 			statFilter{"Server: AfterFilter"}.handler,
 			sizeFilter{"Server: Non Compressed"}.handler,
 		)
 	server.Handle()
 	client := rpc.NewClient(server.URI())
-	client.AddFilter(CompressFilter{}).
+	client.AddFilter(rpc.NewErrorFilter(CompressFilter{})).
 		AddBeforeFilterHandler(
 			(&cacheFilter{Cache: make(map[string][]byte)}).handler,
 			statFilter{"Client: BeforeFilter"}.handler,
@@ -0,0 +1,310 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/balancer.go                                        *
+ *                                                        *
+ * hprose load balancer for Go.                           *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Balancer selects a service address from the uri list for every request
+type Balancer interface {
+	// SetURIList is called when the uri list of the client is changed
+	SetURIList(uriList []string)
+	// Select returns the service address for the request
+	Select(context *ClientContext) string
+	// Done is called when the request sent to uri is finished
+	Done(uri string, err error)
+}
+
+// RoundRobinBalancer selects the service addresses in turn
+type RoundRobinBalancer struct {
+	uriList []string
+	index   uint32
+	locker  sync.RWMutex
+}
+
+// NewRoundRobinBalancer is the constructor of RoundRobinBalancer
+func NewRoundRobinBalancer() *RoundRobinBalancer {
+	return new(RoundRobinBalancer)
+}
+
+// SetURIList of the balancer
+func (b *RoundRobinBalancer) SetURIList(uriList []string) {
+	b.locker.Lock()
+	b.uriList = uriList
+	b.index = 0
+	b.locker.Unlock()
+}
+
+// Select the service address
+func (b *RoundRobinBalancer) Select(context *ClientContext) string {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	n := len(b.uriList)
+	if n == 0 {
+		return ""
+	}
+	i := atomic.AddUint32(&b.index, 1) - 1
+	return b.uriList[int(i%uint32(n))]
+}
+
+// Done does nothing
+func (b *RoundRobinBalancer) Done(uri string, err error) {}
+
+type weightedURI struct {
+	uri     string
+	weight  int
+	current int
+}
+
+// WeightedBalancer selects the service addresses by the smooth weighted
+// round-robin algorithm. The address without weight has a weight of 1.
+type WeightedBalancer struct {
+	weights map[string]int
+	uriList []*weightedURI
+	total   int
+	locker  sync.Mutex
+}
+
+// NewWeightedBalancer is the constructor of WeightedBalancer
+func NewWeightedBalancer(weights map[string]int) *WeightedBalancer {
+	b := new(WeightedBalancer)
+	b.weights = weights
+	return b
+}
+
+// SetURIList of the balancer
+func (b *WeightedBalancer) SetURIList(uriList []string) {
+	b.locker.Lock()
+	b.uriList = make([]*weightedURI, 0, len(uriList))
+	b.total = 0
+	for _, uri := range uriList {
+		weight, ok := b.weights[uri]
+		if !ok {
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+		b.uriList = append(b.uriList, &weightedURI{uri: uri, weight: weight})
+		b.total += weight
+	}
+	b.locker.Unlock()
+}
+
+// Select the service address
+func (b *WeightedBalancer) Select(context *ClientContext) string {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	var best *weightedURI
+	for _, u := range b.uriList {
+		u.current += u.weight
+		if best == nil || u.current > best.current {
+			best = u
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	best.current -= b.total
+	return best.uri
+}
+
+// Done does nothing
+func (b *WeightedBalancer) Done(uri string, err error) {}
+
+type activeCounter struct {
+	uriList []string
+	active  map[string]*int32
+	locker  sync.RWMutex
+}
+
+func (c *activeCounter) SetURIList(uriList []string) {
+	active := make(map[string]*int32, len(uriList))
+	c.locker.Lock()
+	for _, uri := range uriList {
+		if count, ok := c.active[uri]; ok {
+			active[uri] = count
+		} else {
+			active[uri] = new(int32)
+		}
+	}
+	c.uriList = uriList
+	c.active = active
+	c.locker.Unlock()
+}
+
+func (c *activeCounter) count(uri string) int32 {
+	return atomic.LoadInt32(c.active[uri])
+}
+
+func (c *activeCounter) acquire(uri string) string {
+	atomic.AddInt32(c.active[uri], 1)
+	return uri
+}
+
+// Done decreases the outstanding requests count of uri
+func (c *activeCounter) Done(uri string, err error) {
+	c.locker.RLock()
+	if count, ok := c.active[uri]; ok {
+		atomic.AddInt32(count, -1)
+	}
+	c.locker.RUnlock()
+}
+
+// LeastActiveBalancer selects the service address which has the least
+// outstanding requests
+type LeastActiveBalancer struct {
+	activeCounter
+}
+
+// NewLeastActiveBalancer is the constructor of LeastActiveBalancer
+func NewLeastActiveBalancer() *LeastActiveBalancer {
+	return new(LeastActiveBalancer)
+}
+
+// Select the service address
+func (b *LeastActiveBalancer) Select(context *ClientContext) string {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	n := len(b.uriList)
+	if n == 0 {
+		return ""
+	}
+	start := rand.Intn(n)
+	best := b.uriList[start]
+	min := b.count(best)
+	for i := 1; i < n && min > 0; i++ {
+		uri := b.uriList[(start+i)%n]
+		if count := b.count(uri); count < min {
+			best, min = uri, count
+		}
+	}
+	return b.acquire(best)
+}
+
+// RandomTwoChoicesBalancer selects two service addresses at random, and
+// uses the one which has less outstanding requests
+type RandomTwoChoicesBalancer struct {
+	activeCounter
+}
+
+// NewRandomTwoChoicesBalancer is the constructor of RandomTwoChoicesBalancer
+func NewRandomTwoChoicesBalancer() *RandomTwoChoicesBalancer {
+	return new(RandomTwoChoicesBalancer)
+}
+
+// Select the service address
+func (b *RandomTwoChoicesBalancer) Select(context *ClientContext) string {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	n := len(b.uriList)
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return b.acquire(b.uriList[0])
+	}
+	i := rand.Intn(n)
+	j := rand.Intn(n - 1)
+	if j >= i {
+		j++
+	}
+	first, second := b.uriList[i], b.uriList[j]
+	if b.count(second) < b.count(first) {
+		return b.acquire(second)
+	}
+	return b.acquire(first)
+}
+
+// ConsistentHashBalancer selects the service address by the consistent hash
+// of the user data value named Key in the ClientContext, so the requests with
+// the same key are sent to the same service address as long as it is in the
+// uri list. The requests without the key are distributed at random.
+type ConsistentHashBalancer struct {
+	Key      string
+	Replicas int
+	hashes   []uint32
+	ring     map[uint32]string
+	uriList  []string
+	locker   sync.RWMutex
+}
+
+// NewConsistentHashBalancer is the constructor of ConsistentHashBalancer
+func NewConsistentHashBalancer(key string) *ConsistentHashBalancer {
+	b := new(ConsistentHashBalancer)
+	b.Key = key
+	b.Replicas = 100
+	return b
+}
+
+// SetURIList of the balancer
+func (b *ConsistentHashBalancer) SetURIList(uriList []string) {
+	replicas := b.Replicas
+	if replicas <= 0 {
+		replicas = 1
+	}
+	hashes := make([]uint32, 0, len(uriList)*replicas)
+	ring := make(map[uint32]string, len(uriList)*replicas)
+	for _, uri := range uriList {
+		for i := 0; i < replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + uri))
+			if _, ok := ring[hash]; !ok {
+				hashes = append(hashes, hash)
+				ring[hash] = uri
+			}
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	b.locker.Lock()
+	b.hashes = hashes
+	b.ring = ring
+	b.uriList = uriList
+	b.locker.Unlock()
+}
+
+// Select the service address
+func (b *ConsistentHashBalancer) Select(context *ClientContext) string {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	n := len(b.hashes)
+	if n == 0 {
+		return ""
+	}
+	key, ok := context.UserData()[b.Key]
+	if !ok {
+		return b.uriList[rand.Intn(len(b.uriList))]
+	}
+	hash := crc32.ChecksumIEEE([]byte(fmt.Sprint(key)))
+	i := sort.Search(n, func(i int) bool { return b.hashes[i] >= hash })
+	if i == n {
+		i = 0
+	}
+	return b.ring[b.hashes[i]]
+}
+
+// Done does nothing
+func (b *ConsistentHashBalancer) Done(uri string, err error) {}
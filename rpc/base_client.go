@@ -12,7 +12,7 @@
  *                                                        *
  * hprose rpc base client for Go.                         *
  *                                                        *
- * LastModified: Oct 29, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,11 +20,13 @@
 package rpc
 
 import (
+	gocontext "context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"math/rand"
 	"net/url"
 	"reflect"
@@ -39,8 +41,15 @@ import (
 )
 
 type clientTopic struct {
-	callbacks []Callback
-	locker    sync.RWMutex
+	callbacks     []Callback
+	subscriptions []*Subscription
+	resultTypes   []reflect.Type
+	settings      *InvokeSettings
+	native        bool
+	lastSeq       int64
+	// topicParam is true if the callback of a pattern takes the topic name
+	topicParam bool
+	locker     sync.RWMutex
 }
 
 func (ct *clientTopic) addCallback(callback Callback) {
@@ -49,6 +58,26 @@ func (ct *clientTopic) addCallback(callback Callback) {
 	ct.locker.Unlock()
 }
 
+func (ct *clientTopic) addSubscription(s *Subscription) {
+	ct.locker.Lock()
+	ct.subscriptions = append(ct.subscriptions, s)
+	ct.locker.Unlock()
+}
+
+// removeSubscription returns true if nothing receives the topic then
+func (ct *clientTopic) removeSubscription(s *Subscription) bool {
+	ct.locker.Lock()
+	defer ct.locker.Unlock()
+	subscriptions := make([]*Subscription, 0, len(ct.subscriptions))
+	for _, sub := range ct.subscriptions {
+		if sub != s {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+	ct.subscriptions = subscriptions
+	return len(ct.callbacks) == 0 && len(subscriptions) == 0
+}
+
 type topicManager struct {
 	allTopics map[string]map[string]*clientTopic
 	locker    sync.RWMutex
@@ -98,11 +127,20 @@ type baseClient struct {
 	url            *url.URL
 	uri            string
 	uriList        []string
+	balancer       Balancer
 	index          int32
 	failround      int
+	uriLocker      sync.RWMutex
 	retry          int
+	retryPolicy    *RetryPolicy
+	hedgingPolicy  *HedgingPolicy
+	cache          *Cache
 	timeout        time.Duration
 	event          ClientEvent
+	resolver       Resolver
+	closeCtx       gocontext.Context
+	closeClient    gocontext.CancelFunc
+	nativePush     func() bool
 	contextPool    sync.Pool
 	SendAndReceive func([]byte, *ClientContext) ([]byte, error)
 	UserData       map[string]interface{}
@@ -113,14 +151,26 @@ func (client *baseClient) initBaseClient() {
 	client.initHandlerManager()
 	client.timeout = 30 * time.Second
 	client.retry = 10
+	client.cache = NewCache(defaultCacheEntries)
+	client.closeCtx, client.closeClient = gocontext.WithCancel(
+		gocontext.Background())
 	client.contextPool = sync.Pool{
 		New: func() interface{} { return new(ClientContext) },
 	}
-	client.override.invokeHandler = func(
+	invoke := func(
 		name string, args []reflect.Value,
 		context Context) (results []reflect.Value, err error) {
 		return client.invoke(name, args, context.(*ClientContext))
 	}
+	client.override.invokeHandler = func(
+		name string, args []reflect.Value,
+		context Context) (results []reflect.Value, err error) {
+		ctx := context.(*ClientContext)
+		if cache := client.cache; cache != nil && ctx.CacheTTL > 0 && !ctx.cached {
+			return cache.Handler(name, args, context, invoke)
+		}
+		return invoke(name, args, context)
+	}
 	client.override.beforeFilterHandler = func(
 		request []byte, context Context) (response []byte, err error) {
 		return client.beforeFilter(request, context.(*ClientContext))
@@ -134,11 +184,15 @@ func (client *baseClient) initBaseClient() {
 
 // URL returns the current hprose service address.
 func (client *baseClient) URL() *url.URL {
+	client.uriLocker.RLock()
+	defer client.uriLocker.RUnlock()
 	return client.url
 }
 
 // URI returns the current hprose service address.
 func (client *baseClient) URI() string {
+	client.uriLocker.RLock()
+	defer client.uriLocker.RUnlock()
 	return client.uri
 }
 
@@ -152,6 +206,8 @@ func (client *baseClient) SetURI(uri string) {
 
 // URIList returns all of the hprose service addresses
 func (client *baseClient) URIList() []string {
+	client.uriLocker.RLock()
+	defer client.uriLocker.RUnlock()
 	return client.uriList
 }
 
@@ -167,11 +223,45 @@ func shuffleStringSlice(src []string) []string {
 
 // SetURIList set a list of server addresses
 func (client *baseClient) SetURIList(uriList []string) {
-	client.uriList = shuffleStringSlice(uriList)
+	uriList = shuffleStringSlice(uriList)
+	client.uriLocker.Lock()
+	client.uriList = uriList
 	client.index = 0
 	client.failround = 0
-	client.uri = client.uriList[0]
+	client.uri = uriList[0]
 	client.url, _ = url.Parse(client.uri)
+	client.uriLocker.Unlock()
+	if client.balancer != nil {
+		client.balancer.SetURIList(uriList)
+	}
+}
+
+// Balancer returns the load balancer of hprose client
+func (client *baseClient) Balancer() Balancer {
+	return client.balancer
+}
+
+// SetBalancer sets the load balancer of hprose client.
+//
+// The balancer selects the service address for every request. If it is nil,
+// the requests are sent to the current service address, and the next address
+// in the uri list is used only when failswitch is enabled and the request fails.
+func (client *baseClient) SetBalancer(balancer Balancer) {
+	if balancer != nil {
+		balancer.SetURIList(client.URIList())
+	}
+	client.balancer = balancer
+}
+
+// Cache returns the Cache of the methods with CacheTTL
+func (client *baseClient) Cache() *Cache {
+	return client.cache
+}
+
+// SetCache sets the Cache of the methods with CacheTTL, their results are
+// not cached by the client if it is nil.
+func (client *baseClient) SetCache(cache *Cache) {
+	client.cache = cache
 }
 
 // TLSClientConfig returns the tls config of hprose client
@@ -192,6 +282,28 @@ func (client *baseClient) SetRetry(value int) {
 	client.retry = value
 }
 
+// RetryPolicy returns the default retry policy
+func (client *baseClient) RetryPolicy() *RetryPolicy {
+	return client.retryPolicy
+}
+
+// SetRetryPolicy set the default retry policy, it is used when the
+// RetryPolicy of InvokeSettings is nil
+func (client *baseClient) SetRetryPolicy(policy *RetryPolicy) {
+	client.retryPolicy = policy
+}
+
+// HedgingPolicy returns the default hedging policy
+func (client *baseClient) HedgingPolicy() *HedgingPolicy {
+	return client.hedgingPolicy
+}
+
+// SetHedgingPolicy set the default hedging policy, it is used when the
+// HedgingPolicy of InvokeSettings is nil
+func (client *baseClient) SetHedgingPolicy(policy *HedgingPolicy) {
+	client.hedgingPolicy = policy
+}
+
 // Timeout returns the client timeout setting
 func (client *baseClient) Timeout() time.Duration {
 	return client.timeout
@@ -204,6 +316,8 @@ func (client *baseClient) SetTimeout(value time.Duration) {
 
 // Failround return the fail round
 func (client *baseClient) Failround() int {
+	client.uriLocker.RLock()
+	defer client.uriLocker.RUnlock()
 	return client.failround
 }
 
@@ -261,7 +375,11 @@ func (client *baseClient) SetUserData(
 	return client
 }
 
-// UseService build a remote service proxy object with namespace
+// UseService build a remote service proxy object with namespace.
+//
+// The results of the methods with the `cache:"30s"` tag are cached by the
+// Cache of the client, the tag makes the method idempotent, see the
+// CacheTTL of InvokeSettings.
 func (client *baseClient) UseService(
 	remoteService interface{}, namespace ...string) {
 	ns := ""
@@ -281,6 +399,8 @@ func (client *baseClient) getClientContext(
 	context.initBaseContext()
 	context.Client = client
 	context.Retried = 0
+	context.URI = ""
+	context.cached = false
 	if client.UserData != nil {
 		for k, v := range client.UserData {
 			context.SetInterface(k, v)
@@ -288,8 +408,10 @@ func (client *baseClient) getClientContext(
 	}
 	if settings == nil {
 		context.InvokeSettings = InvokeSettings{
-			Timeout: client.timeout,
-			Retry:   client.retry,
+			Timeout:       client.timeout,
+			Retry:         client.retry,
+			RetryPolicy:   client.retryPolicy,
+			HedgingPolicy: client.hedgingPolicy,
 		}
 	} else {
 		if settings.userData != nil {
@@ -298,22 +420,44 @@ func (client *baseClient) getClientContext(
 			}
 		}
 		context.InvokeSettings = *settings
+		if settings.CacheTTL > 0 {
+			context.Idempotent = true
+		}
 		if settings.Timeout <= 0 {
 			context.Timeout = client.timeout
 		}
 		if settings.Retry <= 0 {
 			context.Retry = client.retry
 		}
+		if settings.RetryPolicy == nil {
+			context.RetryPolicy = client.retryPolicy
+		}
+		if settings.HedgingPolicy == nil {
+			context.HedgingPolicy = client.hedgingPolicy
+		}
 	}
 	return context
 }
 
 // Invoke the remote method synchronous
 func (client *baseClient) Invoke(
+	name string,
+	args []reflect.Value,
+	settings *InvokeSettings) (results []reflect.Value, err error) {
+	return client.InvokeContext(gocontext.Background(), name, args, settings)
+}
+
+// InvokeContext invoke the remote method synchronous with a context.Context.
+//
+// The invocation is abandoned when ctx is done, and the deadline of ctx is
+// used as the timeout when it comes earlier than the timeout in settings.
+func (client *baseClient) InvokeContext(
+	ctx gocontext.Context,
 	name string,
 	args []reflect.Value,
 	settings *InvokeSettings) (results []reflect.Value, err error) {
 	context := client.getClientContext(settings)
+	context.setContext(ctx)
 	results, err = client.handlerManager.invokeHandler(name, args, context)
 	if results == nil && len(context.ResultTypes) > 0 {
 		n := len(context.ResultTypes)
@@ -322,6 +466,7 @@ func (client *baseClient) Invoke(
 			results[i] = reflect.New(context.ResultTypes[i]).Elem()
 		}
 	}
+	context.ctx = nil
 	client.contextPool.Put(context)
 	return
 }
@@ -339,7 +484,16 @@ func (client *baseClient) Go(
 }
 
 // Close the client
-func (client *baseClient) Close() {}
+func (client *baseClient) Close() {
+	client.closeClient()
+	if client.resolver != nil {
+		client.resolver.Close()
+	}
+}
+
+func (client *baseClient) setResolver(resolver Resolver) {
+	client.resolver = resolver
+}
 
 func (client *baseClient) fireErrorEvent(name string, err error) {
 	if e := recover(); e != nil {
@@ -355,14 +509,18 @@ func (client *baseClient) fireErrorEvent(name string, err error) {
 func (client *baseClient) beforeFilter(
 	request []byte,
 	context *ClientContext) (response []byte, err error) {
-	request = client.outputFilter(request, context)
+	if request, err = client.outputFilter(request, context); err != nil {
+		return nil, err
+	}
 	if context.Oneway {
 		go client.handlerManager.afterFilterHandler(request, context)
 		return nil, nil
 	}
 	response, err = client.handlerManager.afterFilterHandler(request, context)
-	response = client.inputFilter(response, context)
-	return
+	if err != nil {
+		return nil, err
+	}
+	return client.inputFilter(response, context)
 }
 
 func (client *baseClient) afterFilter(
@@ -373,13 +531,53 @@ func (client *baseClient) afterFilter(
 func (client *baseClient) sendRequest(
 	request []byte,
 	context *ClientContext) (response []byte, err error) {
-	response, err = client.handlerManager.beforeFilterHandler(request, context)
-	if err != nil {
+	if context.Retried == 0 && context.RetryPolicy != nil {
+		context.RetryPolicy.deposit()
+	}
+	policy := context.HedgingPolicy
+	if policy != nil && context.Idempotent && !context.Oneway &&
+		len(client.URIList()) > 1 {
+		response, err = client.hedgeRequest(policy, request, context)
+	} else {
+		uri, balancer := client.selectURI(context)
+		response, err = client.sendTo(request, context, uri, balancer)
+	}
+	if err != nil && !isFilterError(err) {
 		response, err = client.retrySendReqeust(request, err, context)
 	}
 	return
 }
 
+// selectURI returns the service address for the request, and the balancer
+// if the address is selected by it
+func (client *baseClient) selectURI(
+	context *ClientContext) (string, Balancer) {
+	balancer := client.balancer
+	if balancer != nil {
+		if uri := balancer.Select(context); uri != "" {
+			return uri, balancer
+		}
+	}
+	return client.URI(), nil
+}
+
+func (client *baseClient) sendTo(
+	request []byte,
+	context *ClientContext,
+	uri string,
+	balancer Balancer) (response []byte, err error) {
+	context.URI = uri
+	response, err = client.handlerManager.beforeFilterHandler(request, context)
+	if balancer != nil {
+		if isFilterError(err) {
+			balancer.Done(uri, nil)
+		} else {
+			balancer.Done(uri, err)
+		}
+	}
+	return
+}
+
 func (client *baseClient) retrySendReqeust(
 	request []byte,
 	err error,
@@ -387,24 +585,85 @@ func (client *baseClient) retrySendReqeust(
 	if context.Failswitch {
 		client.failswitch()
 	}
+	ctx := context.Context()
+	if e := ctx.Err(); e != nil {
+		return nil, e
+	}
+	// an open circuit fails fast, waiting for it is useless, so the request
+	// is only sent to another address at once
+	circuitOpen := errors.Is(err, ErrCircuitOpen)
+	if circuitOpen && !client.switchable(context) {
+		return nil, err
+	}
+	if policy := context.RetryPolicy; policy != nil {
+		return client.retryWithPolicy(policy, request, err, context)
+	}
 	if context.Idempotent && context.Retried < context.Retry {
 		context.Retried++
 		interval := context.Retried * 500
 		if context.Failswitch {
-			interval -= (len(client.uriList) - 1) * 500
+			interval -= (len(client.URIList()) - 1) * 500
 		}
 		if interval > 5000 {
 			interval = 5000
 		}
-		if interval > 0 {
-			time.Sleep(time.Duration(interval) * time.Millisecond)
+		if interval > 0 && !circuitOpen {
+			err = sleepContext(ctx, time.Duration(interval)*time.Millisecond)
+			if err != nil {
+				return nil, err
+			}
 		}
 		return client.sendRequest(request, context)
 	}
 	return nil, err
 }
 
+// switchable reports whether the retried request can be sent to another
+// service address
+func (client *baseClient) switchable(context *ClientContext) bool {
+	return len(client.URIList()) > 1 &&
+		(context.Failswitch || client.balancer != nil)
+}
+
+func sleepContext(ctx gocontext.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	}
+}
+
+func (client *baseClient) retryWithPolicy(
+	policy *RetryPolicy,
+	request []byte,
+	err error,
+	context *ClientContext) ([]byte, error) {
+	if context.Retried >= policy.maxRetries(context) ||
+		!policy.retryable(err, context) || !policy.withdraw() {
+		return nil, err
+	}
+	context.Retried++
+	// the first round over the uri list is not delayed when failswitch
+	count := len(client.URIList())
+	if (!context.Failswitch || context.Retried >= count) &&
+		!errors.Is(err, ErrCircuitOpen) {
+		n := context.Retried
+		if context.Failswitch {
+			n -= count - 1
+		}
+		err = sleepContext(context.Context(), policy.delay(n))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client.sendRequest(request, context)
+}
+
 func (client *baseClient) failswitch() {
+	client.uriLocker.Lock()
 	n := int32(len(client.uriList))
 	if n > 1 {
 		if atomic.CompareAndSwapInt32(&client.index, n-1, 0) {
@@ -417,6 +676,7 @@ func (client *baseClient) failswitch() {
 	} else {
 		client.failround++
 	}
+	client.uriLocker.Unlock()
 	if event, ok := client.event.(onFailswitchEvent); ok {
 		event.OnFailswitch(client)
 	}
@@ -424,6 +684,9 @@ func (client *baseClient) failswitch() {
 
 func encode(name string, args []reflect.Value, context *ClientContext) []byte {
 	writer := hio.NewWriter(context.Simple)
+	if context.RemoteError {
+		writer.WriteByte(hio.TagError)
+	}
 	writer.WriteByte(hio.TagCall)
 	writer.WriteString(name)
 	if len(args) > 0 || context.ByRef {
@@ -456,18 +719,18 @@ func readMultiResults(
 }
 
 func readResults(
-	reader *hio.Reader, context *ClientContext) (results []reflect.Value) {
-	length := len(context.ResultTypes)
+	reader *hio.Reader, resultTypes []reflect.Type) (results []reflect.Value) {
+	length := len(resultTypes)
 	switch length {
 	case 0:
 		var e interface{}
 		reader.Unserialize(&e)
 	case 1:
 		results = make([]reflect.Value, 1)
-		results[0] = reflect.New(context.ResultTypes[0]).Elem()
+		results[0] = reflect.New(resultTypes[0]).Elem()
 		reader.ReadValue(results[0])
 	default:
-		results = readMultiResults(reader, context.ResultTypes)
+		results = readMultiResults(reader, resultTypes)
 	}
 	return
 }
@@ -491,6 +754,19 @@ func readArgs(reader *hio.Reader, args []reflect.Value) byte {
 	return tag
 }
 
+// readError reads the error after TagError, it is a string or a map of
+// RemoteError
+func readError(reader *hio.Reader) error {
+	tag, _ := reader.ReadByte()
+	reader.UnreadByte()
+	if tag == hio.TagMap {
+		var m map[string]interface{}
+		reader.Unserialize(&m)
+		return newRemoteErrorFromMap(m)
+	}
+	return errors.New(reader.ReadString())
+}
+
 func decode(
 	data []byte,
 	args []reflect.Value,
@@ -522,7 +798,7 @@ func decode(
 	if tag == hio.TagResult {
 		switch context.Mode {
 		case Normal:
-			results = readResults(reader, context)
+			results = readResults(reader, context.ResultTypes)
 		case Serialized:
 			results = make([]reflect.Value, 1)
 			results[0] = reflect.ValueOf(reader.ReadRaw())
@@ -532,7 +808,7 @@ func decode(
 			tag = readArgs(reader, args)
 		}
 	} else if tag == hio.TagError {
-		return nil, errors.New(reader.ReadString())
+		return nil, readError(reader)
 	}
 	if tag != hio.TagEnd {
 		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
@@ -544,9 +820,16 @@ func (client *baseClient) invoke(
 	name string,
 	args []reflect.Value,
 	context *ClientContext) ([]reflect.Value, error) {
+	if err := context.Context().Err(); err != nil {
+		return nil, err
+	}
 	request := encode(name, args, context)
 	response, err := client.sendRequest(request, context)
 	if err != nil {
+		// the FilterError is only reported here
+		if isFilterError(err) {
+			client.fireErrorEvent(name, err)
+		}
 		return nil, err
 	}
 	return decode(response, args, context)
@@ -648,6 +931,42 @@ func getInt64Value(tag reflect.StructTag, key string) int64 {
 	return result
 }
 
+func getFloat64Value(tag reflect.StructTag, key string) float64 {
+	value := tag.Get(key)
+	if value == "" {
+		return 0
+	}
+	result, _ := strconv.ParseFloat(value, 64)
+	return result
+}
+
+func getDurationValue(tag reflect.StructTag, key string) time.Duration {
+	value := tag.Get(key)
+	if value == "" {
+		return 0
+	}
+	result, _ := time.ParseDuration(value)
+	return result
+}
+
+func getRetryPolicy(tag reflect.StructTag) *RetryPolicy {
+	backoff := tag.Get("backoff")
+	jitter := getFloat64Value(tag, "jitter")
+	budget := getFloat64Value(tag, "retrybudget")
+	if backoff == "" && jitter == 0 && budget == 0 {
+		return nil
+	}
+	policy := &RetryPolicy{Jitter: jitter, Budget: budget}
+	if backoff != "" {
+		delays := strings.SplitN(backoff, ",", 2)
+		policy.BaseDelay, _ = time.ParseDuration(strings.TrimSpace(delays[0]))
+		if len(delays) > 1 {
+			policy.MaxDelay, _ = time.ParseDuration(strings.TrimSpace(delays[1]))
+		}
+	}
+	return policy
+}
+
 func getUserData(tag reflect.StructTag) (userdata map[string]interface{}) {
 	value := tag.Get("userdata")
 	if value != "" {
@@ -688,6 +1007,17 @@ func getCallbackResultTypes(ft reflect.Type) ([]reflect.Type, bool) {
 	return results, hasError
 }
 
+func getContext(
+	in []reflect.Value, hasContext bool) (gocontext.Context, []reflect.Value) {
+	if !hasContext {
+		return gocontext.Background(), in
+	}
+	if in[0].IsNil() {
+		return gocontext.Background(), in[1:]
+	}
+	return in[0].Interface().(gocontext.Context), in[1:]
+}
+
 func getIn(in []reflect.Value) []reflect.Value {
 	inlen := len(in)
 	varlen := 0
@@ -710,13 +1040,14 @@ func getIn(in []reflect.Value) []reflect.Value {
 func (client *baseClient) getSyncRemoteMethod(
 	name string,
 	settings *InvokeSettings,
-	isVariadic, hasError bool) func(in []reflect.Value) (out []reflect.Value) {
+	isVariadic, hasError, hasContext bool) func(in []reflect.Value) (out []reflect.Value) {
 	return func(in []reflect.Value) (out []reflect.Value) {
+		ctx, in := getContext(in, hasContext)
 		if isVariadic {
 			in = getIn(in)
 		}
 		var err error
-		out, err = client.Invoke(name, in, settings)
+		out, err = client.InvokeContext(ctx, name, in, settings)
 		if hasError {
 			out = append(out, reflect.ValueOf(&err).Elem())
 		} else if err != nil {
@@ -733,18 +1064,22 @@ func (client *baseClient) getSyncRemoteMethod(
 func (client *baseClient) getAsyncRemoteMethod(
 	name string,
 	settings *InvokeSettings,
-	isVariadic, hasError bool) func(in []reflect.Value) (out []reflect.Value) {
+	isVariadic, hasError, hasContext bool) func(in []reflect.Value) (out []reflect.Value) {
 	return func(in []reflect.Value) (out []reflect.Value) {
 		go func() {
+			ctx, in := getContext(in, hasContext)
 			if isVariadic {
 				in = getIn(in)
 			}
 			callback := in[0]
 			in = in[1:]
-			out, err := client.Invoke(name, in, settings)
+			out, err := client.InvokeContext(ctx, name, in, settings)
 			if hasError {
 				out = append(out, reflect.ValueOf(&err).Elem())
 				err = nil
+			} else if isFilterError(err) {
+				// it has been reported by invoke
+				err = nil
 			}
 			defer client.fireErrorEvent(name, err)
 			callback.Call(out)
@@ -757,10 +1092,15 @@ func (client *baseClient) buildRemoteMethod(
 	f reflect.Value, ft reflect.Type, sf reflect.StructField, ns string) {
 	name := getRemoteMethodName(sf, ns)
 	outTypes, hasError := getResultTypes(ft)
+	hasContext := ft.NumIn() > 0 && ft.In(0) == goContextType
+	cbi := 0
+	if hasContext {
+		cbi = 1
+	}
 	async := false
 	if outTypes == nil && hasError == false {
-		if ft.NumIn() > 0 && ft.In(0).Kind() == reflect.Func {
-			cbft := ft.In(0)
+		if ft.NumIn() > cbi && ft.In(cbi).Kind() == reflect.Func {
+			cbft := ft.In(cbi)
 			if cbft.IsVariadic() {
 				panic("callback can't be variadic function")
 			}
@@ -778,14 +1118,19 @@ func (client *baseClient) buildRemoteMethod(
 		Retry:          int(getInt64Value(sf.Tag, "retry")),
 		Mode:           getResultMode(sf.Tag),
 		Timeout:        time.Duration(getInt64Value(sf.Tag, "timeout")),
+		RetryPolicy:    getRetryPolicy(sf.Tag),
+		CacheTTL:       getDurationValue(sf.Tag, "cache"),
+		RemoteError:    getBoolValue(sf.Tag, "remoteerror"),
 		ResultTypes:    outTypes,
 		userData:       getUserData(sf.Tag),
 	}
 	var fn func(in []reflect.Value) (out []reflect.Value)
 	if async {
-		fn = client.getAsyncRemoteMethod(name, settings, ft.IsVariadic(), hasError)
+		fn = client.getAsyncRemoteMethod(
+			name, settings, ft.IsVariadic(), hasError, hasContext)
 	} else {
-		fn = client.getSyncRemoteMethod(name, settings, ft.IsVariadic(), hasError)
+		fn = client.getSyncRemoteMethod(
+			name, settings, ft.IsVariadic(), hasError, hasContext)
 	}
 	if f.Kind() == reflect.Ptr {
 		fp := reflect.New(ft)
@@ -856,30 +1201,299 @@ func (client *baseClient) processCallback(
 	}
 }
 
-func (client *baseClient) subscribe(
-	name string, id string, settings *InvokeSettings) {
-	resultTypes := settings.ResultTypes
-	settings.ResultTypes = []reflect.Type{interfaceType}
-	args := []reflect.Value{reflect.ValueOf(id)}
+// notify the callbacks and the channel subscriptions of the topic
+func (client *baseClient) notify(
+	name string, topic *clientTopic, results []reflect.Value) {
+	topic.locker.RLock()
+	callbacks := topic.callbacks
+	subscriptions := topic.subscriptions
+	topic.locker.RUnlock()
+	if len(callbacks) > 0 {
+		client.processCallback(name, callbacks, topic.resultTypes, results, nil)
+	}
+	for _, s := range subscriptions {
+		client.processCallback(
+			name, []Callback{s.send}, s.resultTypes, results, nil)
+	}
+}
+
+func (client *baseClient) subscribe(name string, id string) {
+	failures := 0
 	for {
-		topic := client.getTopic(name, id)
-		if topic == nil {
+		sequenced, done := client.handshake(name, id)
+		if done {
+			return
+		}
+		// poll returns when an error occurs, then subscribe again
+		polled, err := client.poll(name, id, sequenced)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, ErrTimeout) {
+			continue
+		}
+		if !client.subscribeFailed(name, id, err) {
 			return
 		}
-		topic.locker.RLock()
-		callbacks := topic.callbacks
-		topic.locker.RUnlock()
-		results, err := client.Invoke(name, args, settings)
-		if !results[0].IsNil() {
-			client.processCallback(name, callbacks, resultTypes, results, err)
+		if polled {
+			failures = 0
+		}
+		if sleepContext(client.closeCtx, resubscribeDelay(failures)) != nil {
+			return
+		}
+		failures++
+	}
+}
+
+// resubscribeDelay returns the delay before the topic is subscribed again
+// after it failed n times in a row, it is doubled from 500ms up to 5s.
+func resubscribeDelay(n int) time.Duration {
+	delay := 5 * time.Second
+	if n < 4 {
+		delay = (500 * time.Millisecond) << uint(n)
+	}
+	return delay
+}
+
+// subscribeFailed reports the error of the topic by the OnError event, and
+// closes the channel subscriptions of the topic with it. It returns false
+// if nothing receives the topic then, and the topic is unsubscribed.
+func (client *baseClient) subscribeFailed(name string, id string, err error) bool {
+	client.fireErrorEvent(name, err)
+	topic := client.getTopic(name, id)
+	if topic == nil {
+		return false
+	}
+	topic.locker.Lock()
+	subscriptions := topic.subscriptions
+	topic.subscriptions = nil
+	empty := len(topic.callbacks) == 0
+	topic.locker.Unlock()
+	for _, s := range subscriptions {
+		s.close(err)
+	}
+	if empty {
+		client.Unsubscribe(name, id)
+		return false
+	}
+	return true
+}
+
+// handshake subscribes the topic by #subscribe, it returns done if the
+// messages are pushed natively over the persistent connection, or the topic
+// is unsubscribed. The service acknowledges the sequence numbers if
+// sequenced is true, otherwise it is an old service which doesn't support
+// #subscribe.
+func (client *baseClient) handshake(
+	name string, id string) (sequenced bool, done bool) {
+	topic := client.getTopic(name, id)
+	if topic == nil {
+		return false, true
+	}
+	native := client.nativePush != nil && client.nativePush()
+	topic.locker.Lock()
+	topic.native = native
+	lastSeq := topic.lastSeq
+	topic.locker.Unlock()
+	settings := *topic.settings
+	settings.ResultTypes = []reflect.Type{boolType}
+	args := []reflect.Value{
+		reflect.ValueOf(name),
+		reflect.ValueOf(id),
+		reflect.ValueOf(lastSeq),
+		reflect.ValueOf(settings.Metadata),
+	}
+	results, err := client.Invoke("#subscribe", args, &settings)
+	if native && err == nil && results[0].Bool() {
+		return true, true
+	}
+	if native {
+		topic.locker.Lock()
+		native = topic.native
+		topic.native = false
+		topic.locker.Unlock()
+		// resubscribe has restarted the subscription when native is false
+		if !native {
+			return false, true
+		}
+	}
+	return err == nil, false
+}
+
+// poll the topic until an error occurs, it returns nil if the topic is
+// unsubscribed or the client is closed, and polled is true if the topic is
+// polled successfully before the error.
+func (client *baseClient) poll(
+	name string, id string, sequenced bool) (polled bool, err error) {
+	for {
+		topic := client.getTopic(name, id)
+		if topic == nil || client.closeCtx.Err() != nil {
+			return polled, nil
+		}
+		method := name
+		args := []reflect.Value{reflect.ValueOf(id)}
+		if sequenced {
+			method = "#poll"
+			topic.locker.RLock()
+			args = []reflect.Value{
+				reflect.ValueOf(name),
+				reflect.ValueOf(id),
+				reflect.ValueOf(topic.lastSeq),
+				reflect.ValueOf(topic.settings.Metadata),
+			}
+			topic.locker.RUnlock()
+		}
+		results, err := client.Invoke(method, args, topic.settings)
+		if err != nil {
+			return polled, err
+		}
+		polled = true
+		if results[0].IsNil() {
+			continue
+		}
+		if sequenced {
+			client.deliver(name, id, topic, results[0].Interface())
+			continue
+		}
+		client.notify(name, topic, results)
+	}
+}
+
+func toSeq(v interface{}) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case *big.Int:
+		return v.Int64()
+	}
+	return 0
+}
+
+// deliver the sequenced messages to the callbacks of the topic, the
+// payload is encoded by topic.sequencedPayload of the service.
+func (client *baseClient) deliver(
+	name string, id string, topic *clientTopic, payload interface{}) {
+	list, ok := payload.([]interface{})
+	if !ok || len(list) == 0 {
+		return
+	}
+	batch, _ := list[0].(bool)
+	// the messages of a pattern are pushed with the topic names
+	unwrap := isTopicPattern(name) && (batch || !topic.topicParam)
+	data := make([]interface{}, 0, len(list)-1)
+	var gaps [][2]int64
+	topic.locker.Lock()
+	for _, m := range list[1:] {
+		message, ok := m.([]interface{})
+		if !ok || len(message) != 3 {
+			continue
+		}
+		seq, prev := toSeq(message[0]), toSeq(message[1])
+		if prev != topic.lastSeq {
+			gaps = append(gaps, [2]int64{topic.lastSeq, seq})
+		}
+		topic.lastSeq = seq
+		if pair, ok := message[2].([]interface{}); unwrap && ok && len(pair) == 2 {
+			message[2] = pair[1]
+		}
+		data = append(data, message[2])
+	}
+	topic.locker.Unlock()
+	for _, gap := range gaps {
+		client.firePushGapEvent(name, id, gap[0], gap[1])
+	}
+	if batch {
+		client.notify(name, topic, []reflect.Value{reflect.ValueOf(&data).Elem()})
+		return
+	}
+	for i := range data {
+		client.notify(name, topic, []reflect.Value{reflect.ValueOf(&data[i]).Elem()})
+	}
+}
+
+func (client *baseClient) firePushGapEvent(
+	name string, id string, lastSeq int64, seq int64) {
+	defer func() {
+		recover()
+	}()
+	if event, ok := client.event.(pushGapEvent); ok {
+		event.OnPushGap(name, id, lastSeq, seq)
+	}
+}
+
+// push is invoked by the service to deliver the messages of the topic
+// natively, it returns false if the topic is unsubscribed.
+func (client *baseClient) push(name string, id string, payload interface{}) bool {
+	topic := client.getTopic(name, id)
+	if topic == nil {
+		return false
+	}
+	client.deliver(name, id, topic, payload)
+	return true
+}
+
+// resubscribe the native push topics when a connection is closed, because
+// the server drops the subscriptions over the connection.
+func (client *baseClient) resubscribe() {
+	client.topicManager.locker.RLock()
+	for name, topics := range client.allTopics {
+		for id, topic := range topics {
+			topic.locker.Lock()
+			if topic.native {
+				topic.native = false
+				go client.subscribe(name, id)
+			}
+			topic.locker.Unlock()
 		}
 	}
+	client.topicManager.locker.RUnlock()
+}
+
+// reverseRequest returns the #reverse request, it is sent over the new
+// connections to tell the server that the client handles its requests.
+func (client *baseClient) reverseRequest() []byte {
+	writer := hio.NewWriter(true)
+	writer.WriteByte(hio.TagCall)
+	writer.WriteString("#reverse")
+	// the empty arguments make the service pass the context to it
+	writer.Reset()
+	writer.WriteSlice(nil)
+	writer.WriteByte(hio.TagEnd)
+	context := client.getClientContext(nil)
+	defer client.contextPool.Put(context)
+	request, err := client.outputFilter(writer.Bytes(), context)
+	if err != nil {
+		return nil
+	}
+	return request
+}
+
+func (client *baseClient) publishPush(service *ReverseService) {
+	service.AddFunction("#push", client.push, Options{Simple: true})
 }
 
 // Subscribe a push topic
 func (client *baseClient) Subscribe(
 	name string, id string,
 	settings *InvokeSettings, callback interface{}) (err error) {
+	return client.SubscribeFrom(name, id, 0, settings, callback)
+}
+
+// SubscribeFrom subscribes a push topic after the message of the sequence
+// number seq, the messages after seq retained by the service are pushed
+// again, seq is the LastSeq before the client is restarted.
+//
+// The gaps of the sequence numbers are reported by the OnPushGap event.
+//
+// The name can be a topic pattern, such as "orders.eu.*" or "orders.#", the
+// callback receives the messages pushed to all of the matching topics. If
+// the callback takes two parameters and the first one is a string, the name
+// of the topic is passed to it.
+func (client *baseClient) SubscribeFrom(
+	name string, id string, seq int64,
+	settings *InvokeSettings, callback interface{}) (err error) {
 	if id == "" {
 		id, err = client.AutoID()
 		if err != nil {
@@ -897,6 +1511,16 @@ func (client *baseClient) Subscribe(
 		}
 		f.Call(results)
 	}
+	client.subscribeTopic(name, id, seq, settings, resultTypes,
+		func(topic *clientTopic) { topic.addCallback(cb) })
+	return nil
+}
+
+// subscribeTopic starts the subscription of the topic if it is not
+// subscribed, add attaches the receiver to the topic.
+func (client *baseClient) subscribeTopic(
+	name string, id string, seq int64, settings *InvokeSettings,
+	resultTypes []reflect.Type, add func(topic *clientTopic)) {
 	if settings == nil {
 		settings = new(InvokeSettings)
 	}
@@ -905,20 +1529,68 @@ func (client *baseClient) Subscribe(
 	settings.Mode = Normal
 	settings.Oneway = false
 	settings.Simple = true
-	settings.ResultTypes = resultTypes
+	settings.ResultTypes = []reflect.Type{interfaceType}
 	client.createTopic(name)
 	topic := client.getTopic(name, id)
 	if topic == nil {
 		topic = new(clientTopic)
-		topic.addCallback(cb)
+		topic.resultTypes = resultTypes
+		topic.settings = settings
+		topic.lastSeq = seq
+		topic.topicParam = isTopicPattern(name) && len(resultTypes) == 2 &&
+			resultTypes[0] == stringType
+		add(topic)
 		client.topicManager.locker.Lock()
 		client.allTopics[name][id] = topic
 		client.topicManager.locker.Unlock()
-		go client.subscribe(name, id, settings)
+		go client.subscribe(name, id)
 	} else {
-		topic.addCallback(cb)
+		add(topic)
 	}
-	return nil
+}
+
+// SubscribeChan subscribes a push topic, the messages are delivered into
+// the channel which chanPtr points to. If the channel is nil, it is made
+// with a buffer of 16 messages, make it before if you need another buffer.
+// The delivery of the topic waits for the receiver when the buffer is full.
+//
+// The channel is closed when the Subscription is closed, or the topic
+// fails to subscribe again, then Err returns the error.
+func (client *baseClient) SubscribeChan(
+	name string, id string,
+	settings *InvokeSettings, chanPtr interface{}) (*Subscription, error) {
+	p := reflect.ValueOf(chanPtr)
+	if p.Kind() != reflect.Ptr || p.Elem().Kind() != reflect.Chan ||
+		p.Elem().Type().ChanDir()&reflect.SendDir == 0 {
+		return nil, errors.New("SubscribeChan: chanPtr must be a pointer to a channel")
+	}
+	if id == "" {
+		var err error
+		if id, err = client.AutoID(); err != nil {
+			return nil, err
+		}
+	}
+	channel := p.Elem()
+	if channel.IsNil() {
+		channel.Set(reflect.MakeChan(channel.Type(), 16))
+	}
+	s := newSubscription(client, name, id, channel)
+	client.subscribeTopic(name, id, 0, settings, s.resultTypes,
+		func(topic *clientTopic) { topic.addSubscription(s) })
+	return s, nil
+}
+
+// LastSeq returns the sequence number of the last message received from
+// the push topic, it is 0 if the topic is not subscribed or the service
+// doesn't support the sequence numbers.
+func (client *baseClient) LastSeq(name string, id string) int64 {
+	topic := client.getTopic(name, id)
+	if topic == nil {
+		return 0
+	}
+	topic.locker.RLock()
+	defer topic.locker.RUnlock()
+	return topic.lastSeq
 }
 
 // Unsubscribe a push topic
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose base service for Go.                            *
  *                                                        *
- * LastModified: Oct 29, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,11 +20,13 @@
 package rpc
 
 import (
+	gocontext "context"
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hprose/hprose-golang/io"
@@ -35,6 +37,7 @@ type baseService struct {
 	methodManager
 	handlerManager
 	filterManager
+	drainer
 	FixArguments func(args []reflect.Value, context ServiceContext)
 	Event        ServiceEvent
 	Debug        bool
@@ -43,6 +46,10 @@ type baseService struct {
 	ErrorDelay   time.Duration
 	UserData     map[string]interface{}
 	topics       map[string]*topic
+	topicTree    *topicTree
+	families     map[string]*topicFamily
+	broker       PushBroker
+	node         *pushNode
 	topicLock    sync.RWMutex
 }
 
@@ -51,9 +58,15 @@ func defaultFixArguments(args []reflect.Value, context ServiceContext) {
 	typ := args[i].Type()
 	if typ == interfaceType || typ == contextType || typ == serviceContextType {
 		args[i] = reflect.ValueOf(context)
+	} else if typ == goContextType {
+		args[i] = reflect.ValueOf(context.Context())
 	}
 }
 
+func hasContextParam(ft reflect.Type) bool {
+	return ft.NumIn() > 0 && ft.In(0) == goContextType
+}
+
 func (service *baseService) initBaseService() {
 	service.initMethodManager()
 	service.initHandlerManager()
@@ -61,7 +74,15 @@ func (service *baseService) initBaseService() {
 	service.Heartbeat = 3 * time.Second
 	service.ErrorDelay = 10 * time.Second
 	service.topics = make(map[string]*topic)
+	service.topicTree = newTopicTree()
+	service.families = make(map[string]*topicFamily)
+	service.node = &pushNode{service}
+	service.SetPushBroker(NewMemoryBroker())
+	service.initDrainer()
 	service.AddFunction("#", util.UUIDv4, Options{Simple: true})
+	service.AddFunction("#subscribe", service.subscribe, Options{Simple: true})
+	service.AddFunction("#reverse", service.acceptReverse, Options{Simple: true})
+	service.AddFunction("#poll", service.pollTopic, Options{})
 	service.override.invokeHandler = func(
 		name string, args []reflect.Value,
 		context Context) (results []reflect.Value, err error) {
@@ -197,13 +218,26 @@ func callService(
 		return missingMethod(name, args, context), nil
 	}
 	ft := function.Type()
+	offset := 0
+	if hasContextParam(ft) {
+		offset = 1
+	}
 	if !ft.IsVariadic() {
 		count := len(args)
-		n := ft.NumIn()
+		n := ft.NumIn() - offset
 		if n < count {
 			args = args[:n]
 		}
 	}
+	if offset > 0 {
+		ctx := gocontext.Background()
+		if !remoteMethod.Oneway {
+			ctx = context.Context()
+		}
+		in := make([]reflect.Value, 0, len(args)+1)
+		in = append(in, reflect.ValueOf(&ctx).Elem())
+		args = append(in, args...)
+	}
 	results = function.Call(args)
 	n := ft.NumOut()
 	if n == 0 {
@@ -319,7 +353,11 @@ func (service *baseService) sendError(err error, context Context) []byte {
 	err = fireErrorEvent(service.Event, err, context)
 	w := io.NewWriter(true)
 	w.WriteByte(io.TagError)
-	w.WriteString(getErrorMessage(err, service.Debug))
+	if e := toRemoteError(err); e != nil && context.GetBool("remoteError") {
+		w.Serialize(e.toMap(service.Debug))
+	} else {
+		w.WriteString(getErrorMessage(err, service.Debug))
+	}
 	return w.Bytes()
 }
 
@@ -337,9 +375,16 @@ func invoke(
 		return nil, errors.New("Can't find this method " + name)
 	}
 	if context.Method().Oneway {
+		counter, _ := context.Service().(inflightCounter)
+		if counter != nil {
+			counter.begin()
+		}
 		go func() {
 			defer func() {
 				recover()
+				if counter != nil {
+					counter.end()
+				}
 			}()
 			callService(name, args, context)
 		}()
@@ -361,19 +406,23 @@ func readArguments(
 	}
 	count := reader.ReadCount()
 	ft := method.Function.Type()
-	n := ft.NumIn()
+	offset := 0
+	if hasContextParam(ft) {
+		offset = 1
+	}
+	n := ft.NumIn() - offset
 	if ft.IsVariadic() {
 		n--
 	}
 	max := util.Max(n, count)
 	args = make([]reflect.Value, max)
 	for i := 0; i < n; i++ {
-		args[i] = reflect.New(ft.In(i)).Elem()
+		args[i] = reflect.New(ft.In(i + offset)).Elem()
 	}
 	if n < count {
 		if ft.IsVariadic() {
 			for i := n; i < count; i++ {
-				args[i] = reflect.New(ft.In(n).Elem()).Elem()
+				args[i] = reflect.New(ft.In(n + offset).Elem()).Elem()
 			}
 		} else {
 			for i := n; i < count; i++ {
@@ -426,9 +475,11 @@ func (service *baseService) doSingleInvoke(
 	name := reader.ReadString()
 	alias := strings.ToLower(name)
 	method := service.RemoteMethods[alias]
+	// the context is shared by all calls in a batch request
+	context.setIsMissingMethod(method == nil)
+	context.setByRef(false)
 	if method == nil {
 		method = service.RemoteMethods["*"]
-		context.setIsMissingMethod(true)
 	}
 	tag = reader.CheckTags([]byte{io.TagList, io.TagEnd, io.TagCall})
 	var args []reflect.Value
@@ -442,6 +493,15 @@ func (service *baseService) doSingleInvoke(
 		}
 	}
 	context.setMethod(method)
+	if method != nil && method.Timeout > 0 && !method.Oneway {
+		ctx := context.Context()
+		timeoutCtx, cancel := gocontext.WithTimeout(ctx, method.Timeout)
+		context.setContext(timeoutCtx)
+		defer func() {
+			cancel()
+			context.setContext(ctx)
+		}()
+	}
 	result, err := service.beforeInvoke(name, args, context)
 	if err != nil {
 		return service.sendError(err, context), tag
@@ -481,6 +541,14 @@ func (service *baseService) afterFilter(
 	if err != nil {
 		return nil, err
 	}
+	// the errors are sent as RemoteError only when the client requests it
+	// by TagError before the calls, the old clients read the messages
+	if tag == io.TagError {
+		context.SetBool("remoteError", true)
+		if tag, err = reader.ReadByte(); err != nil {
+			return nil, err
+		}
+	}
 	switch tag {
 	case io.TagCall:
 		return service.doInvoke(reader, context), nil
@@ -502,16 +570,21 @@ func (service *baseService) delayError(
 
 func (service *baseService) beforeFilter(
 	request []byte, context ServiceContext) (response []byte, err error) {
-	request = service.inputFilter(request, context)
-	response, err = service.afterFilterHandler(request, context)
+	request, err = service.inputFilter(request, context)
+	if err == nil {
+		response, err = service.afterFilterHandler(request, context)
+	}
 	if err != nil {
 		response = service.delayError(err, context)
 	}
-	return service.outputFilter(response, context), nil
+	// the error response of a failed output filter is sent without filters
+	return service.outputFilter(response, context)
 }
 
 // Handle the hprose request and return the hprose response
 func (service *baseService) Handle(request []byte, context Context) []byte {
+	service.begin()
+	defer service.end()
 	if service.UserData != nil {
 		for k, v := range service.UserData {
 			context.SetInterface(k, v)
@@ -525,169 +598,648 @@ func (service *baseService) Handle(request []byte, context Context) []byte {
 }
 
 func fireSubscribeEvent(
-	topic string,
-	id string,
+	t *topic,
+	s *subscriber,
+	context ServiceContext,
 	service *baseService) {
 	defer func() {
 		recover()
 	}()
-	if event, ok := service.Event.(subscribeEvent); ok {
-		event.OnSubscribe(topic, id, service)
+	switch event := service.Event.(type) {
+	case subscribeEvent:
+		event.OnSubscribe(t.name, s.id, service)
+	case subscribeEvent2:
+		event.OnSubscribe(t.subscriberInfo(s), service, context)
 	}
 }
 
 func fireUnsubscribeEvent(
-	topic string,
-	id string,
+	t *topic,
+	s *subscriber,
 	service *baseService) {
 	defer func() {
 		recover()
 	}()
-	if event, ok := service.Event.(unsubscribeEvent); ok {
-		event.OnUnsubscribe(topic, id, service)
+	switch event := service.Event.(type) {
+	case unsubscribeEvent:
+		event.OnUnsubscribe(t.name, s.id, service)
+	case unsubscribeEvent2:
+		event.OnUnsubscribe(t.subscriberInfo(s), service)
+	}
+}
+
+func (service *baseService) offline(t *topic, s *subscriber) {
+	service.offlineIf(t, s, nil)
+}
+
+// offlineIf removes the subscriber if cond returns true, the queued messages
+// are dropped.
+func (service *baseService) offlineIf(
+	t *topic, s *subscriber, cond func(s *subscriber) bool) {
+	if queue, ok := t.remove(s, cond); ok {
+		doneMessages(queue, false)
+		fireUnsubscribeEvent(t, s, service)
+		if t.dynamic {
+			service.removeTopic(t)
+		}
 	}
 }
 
-func (service *baseService) offline(t *topic, topic string, id string) {
-	if t.exist(id) {
-		t.remove(id)
-		fireUnsubscribeEvent(topic, id, service)
+// removeTopic removes the topic created by the subscriptions when it has no
+// subscriber, so the clients can't grow the topics without limit.
+func (service *baseService) removeTopic(t *topic) {
+	service.topicLock.Lock()
+	defer service.topicLock.Unlock()
+	if service.topics[t.name] == t && t.isEmpty() {
+		delete(service.topics, t.name)
+		service.topicTree.remove(t.name)
 	}
 }
 
-// Publish the hprose push topic
+// attach calls f to subscribe the topic, the topic is resolved again if it
+// has been removed. It holds the read lock of the topics while f is called,
+// so the topic isn't removed before f subscribes it.
+func (service *baseService) attach(t *topic, f func(t *topic)) *topic {
+	for {
+		service.topicLock.RLock()
+		if service.topics[t.name] == t {
+			f(t)
+			service.topicLock.RUnlock()
+			return t
+		}
+		service.topicLock.RUnlock()
+		t = service.resolveTopic(t.name)
+	}
+}
+
+// Shutdown releases the push subscribers, and waits for the in-flight
+// requests to finish until ctx is done.
+//
+// It doesn't close the listeners and the connections, the servers override
+// it to do that.
+func (service *baseService) Shutdown(ctx gocontext.Context) error {
+	return service.drain(ctx)
+}
+
+// Publish the hprose push topic.
+//
+// The messages pushed to a subscriber are queued until they are delivered,
+// the queue is configured by option. The clients over websocket and full
+// duplex socket connections receive the messages natively by the #push
+// method, the other clients long poll the topic.
+//
+// The topic can be a pattern, such as "orders.eu.*" or "orders.#", then the
+// clients can subscribe any topic or pattern matching it, and the messages
+// pushed to a topic are received by all of the subscriptions matching it.
 func (service *baseService) Publish(
 	topic string,
 	timeout time.Duration,
-	heartbeat time.Duration) Service {
+	heartbeat time.Duration,
+	option ...TopicOptions) Service {
 	if timeout <= 0 {
 		timeout = service.Timeout
 	}
 	if heartbeat <= 0 {
 		heartbeat = service.Heartbeat
 	}
-	t := newTopic(heartbeat)
+	family := &topicFamily{pattern: topic, timeout: timeout, heartbeat: heartbeat}
+	if len(option) > 0 {
+		family.options = option[0]
+	}
 	service.topicLock.Lock()
-	service.topics[topic] = t
+	service.families[topic] = family
 	service.topicLock.Unlock()
-	return service.AddFunction(topic, func(id string) interface{} {
-		message := t.get(id)
-		if message == nil {
-			message = make(chan interface{})
-			t.put(id, message)
-			fireSubscribeEvent(topic, id, service)
-		}
-		select {
-		case result := <-message:
-			return result
-		case <-time.After(timeout):
-			service.offline(t, topic, id)
-			return nil
-		}
+	if isTopicPattern(topic) {
+		return service
+	}
+	t := service.addTopic(family, topic)
+	// the old clients poll the topic by its name, see subscribe.
+	return service.AddFunction(topic, func(
+		id string, context ServiceContext) (interface{}, error) {
+		return service.poll(t, id, false, 0, context, nil)
 	}, Options{})
 }
 
-func (service *baseService) getTopic(topic string) (t *topic) {
+// topicFamily is a published topic or pattern
+type topicFamily struct {
+	pattern   string
+	timeout   time.Duration
+	heartbeat time.Duration
+	options   TopicOptions
+}
+
+// addTopic creates the topic of the name which matches the family
+func (service *baseService) addTopic(family *topicFamily, name string) *topic {
+	service.topicLock.Lock()
+	defer service.topicLock.Unlock()
+	return service.newTopic(family, name)
+}
+
+// newTopic must be called with the topicLock held
+func (service *baseService) newTopic(family *topicFamily, name string) *topic {
+	t := newTopic(name, family.timeout, family.heartbeat, family.options)
+	t.expire = func(s *subscriber) {
+		service.offlineIf(t, s, isIdle)
+	}
+	service.topics[name] = t
+	service.topicTree.put(name, t)
+	return t
+}
+
+// findFamily returns the published topic or pattern matching name,
+// it must be called with the topicLock held.
+func (service *baseService) findFamily(name string) *topicFamily {
+	if family := service.families[name]; family != nil {
+		return family
+	}
+	for pattern, family := range service.families {
+		if matchTopic(pattern, name) {
+			return family
+		}
+	}
+	return nil
+}
+
+// findTopic returns the subscribed topic or pattern, it returns nil if
+// nobody subscribes it, and it panics if it is not published.
+func (service *baseService) findTopic(name string) (t *topic) {
 	service.topicLock.RLock()
-	t = service.topics[topic]
+	defer service.topicLock.RUnlock()
+	if t = service.topics[name]; t == nil && service.findFamily(name) == nil {
+		panic("topic \"" + name + "\" is not published.")
+	}
+	return
+}
+
+// resolveTopic returns the topic or pattern to subscribe, it returns nil if
+// it is not published.
+func (service *baseService) resolveTopic(name string) *topic {
+	service.topicLock.RLock()
+	t := service.topics[name]
+	family := service.findFamily(name)
 	service.topicLock.RUnlock()
+	if t != nil || family == nil {
+		return t
+	}
+	service.topicLock.Lock()
+	defer service.topicLock.Unlock()
+	if t = service.topics[name]; t == nil {
+		t = service.newTopic(family, name)
+		t.dynamic = true
+	}
+	return t
+}
+
+// matchTopics returns the subscribed topics and patterns which receive the
+// messages pushed to the topic, it panics if the topic is not published.
+func (service *baseService) matchTopics(name string) []*topic {
+	topics, published := service.lookupTopics(name)
+	if !published {
+		panic("topic \"" + name + "\" is not published.")
+	}
+	return topics
+}
+
+// subscribedTopics is the same as matchTopics, but it returns nil if the
+// topic is not published.
+func (service *baseService) subscribedTopics(name string) []*topic {
+	topics, _ := service.lookupTopics(name)
+	return topics
+}
+
+func (service *baseService) lookupTopics(name string) ([]*topic, bool) {
+	service.topicLock.RLock()
+	defer service.topicLock.RUnlock()
+	if service.findFamily(name) == nil {
+		return nil, false
+	}
+	return service.topicTree.match(name), true
+}
+
+// pollTopic is invoked by the sequenced clients to poll the topic or
+// pattern, ack is the sequence number of the last received message,
+// metadata is attached by the client to its presence.
+func (service *baseService) pollTopic(
+	topic string, id string, ack int64,
+	metadata map[string]interface{},
+	context ServiceContext) (interface{}, error) {
+	t := service.resolveTopic(topic)
 	if t == nil {
-		panic("topic \"" + topic + "\" is not published.")
+		return nil, errors.New("topic \"" + topic + "\" is not published.")
 	}
-	return
+	return service.poll(t, id, true, ack, context, metadata)
 }
 
-func (service *baseService) unicast(
-	t *topic, topic string, id string, result interface{}, callback func(bool)) {
-	message := t.get(id)
-	if message == nil {
-		if callback != nil {
-			callback(false)
-		}
-		return
+// poll returns the messages pushed to the subscriber, it returns nil if
+// no message is pushed before the timeout of the topic.
+func (service *baseService) poll(
+	t *topic, id string, sequenced bool, ack int64,
+	context ServiceContext, metadata map[string]interface{}) (interface{}, error) {
+	closing := service.closingChan()
+	select {
+	case <-closing:
+		return nil, errServiceIsShuttingDown
+	default:
 	}
-	go func() {
-		select {
-		case message <- result:
-			if callback != nil {
-				callback(true)
+	var s *subscriber
+	var created bool
+	var acked []pushMessage
+	t = service.attach(t, func(found *topic) {
+		s, created, acked = found.beginPoll(id, sequenced, ack, context, metadata)
+	})
+	defer t.endPoll(s)
+	doneMessages(acked, true)
+	if created {
+		fireSubscribeEvent(t, s, context, service)
+	}
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	for {
+		messages, signal := t.poll(s, sequenced)
+		if messages != nil {
+			if sequenced {
+				return t.sequencedPayload(messages), nil
 			}
-			break
-		case <-time.After(t.heartbeat):
-			service.offline(t, topic, id)
-			if callback != nil {
-				callback(false)
+			doneMessages(messages, true)
+			return t.payload(messages), nil
+		}
+		select {
+		case <-signal:
+			if !t.isPolling(s) {
+				return nil, nil
 			}
+		case <-timer.C:
+			return nil, nil
+		case <-closing:
+			// the error makes the client retry later instead of polling
+			// again immediately
+			service.offline(t, s)
+			return nil, errServiceIsShuttingDown
 		}
-	}()
+	}
+}
+
+type reverseContext interface {
+	getReverse() *reverseTransport
 }
 
-// IDList returns the push client id list
+func getReverse(context ServiceContext) *reverseTransport {
+	if c, ok := context.(reverseContext); ok {
+		return c.getReverse()
+	}
+	return nil
+}
+
+// acceptReverse is invoked by the client which handles the requests of the
+// server over its persistent connection, it returns false if the connection
+// doesn't support it.
+func (service *baseService) acceptReverse(context ServiceContext) bool {
+	reverse := getReverse(context)
+	if reverse == nil {
+		return false
+	}
+	reverse.enable()
+	return reverse.isAvailable()
+}
+
+// subscribe is invoked by the client to receive the messages of the topic
+// over its persistent connection, instead of long polling. It returns false
+// if the connection doesn't support it, then the client falls back to long
+// polling with the sequence numbers.
+//
+// ack is the sequence number of the last message received by the client,
+// the retained messages after it are sent again. metadata is attached by
+// the client to its presence.
+func (service *baseService) subscribe(
+	name string, id string, ack int64,
+	metadata map[string]interface{}, context ServiceContext) bool {
+	t := service.resolveTopic(name)
+	if t == nil || service.isClosing() {
+		return false
+	}
+	reverse := getReverse(context)
+	if reverse == nil {
+		return false
+	}
+	// the client which subscribes natively handles the #push requests
+	if reverse.enable(); !reverse.isAvailable() {
+		return false
+	}
+	var s *subscriber
+	var created, send bool
+	var acked []pushMessage
+	t = service.attach(t, func(found *topic) {
+		s, created, send, acked = found.subscribeNative(id, reverse, ack, context, metadata)
+	})
+	doneMessages(acked, true)
+	if created {
+		fireSubscribeEvent(t, s, context, service)
+	}
+	reverse.addCloseHandler(func() {
+		service.offlineIf(t, s, func(s *subscriber) bool {
+			return s.native == reverse
+		})
+	})
+	if send {
+		go service.sendNative(t, name, s)
+	}
+	return true
+}
+
+// sendNative sends the queued messages to the client by invoking its #push
+// method, until the queue is empty.
+func (service *baseService) sendNative(t *topic, topic string, s *subscriber) {
+	settings := &InvokeSettings{
+		Simple:      true,
+		Timeout:     t.heartbeat,
+		ResultTypes: []reflect.Type{boolType},
+	}
+	for {
+		messages, reverse := t.takeNative(s)
+		if messages == nil {
+			return
+		}
+		data := t.sequencedPayload(messages)
+		args := []reflect.Value{
+			reflect.ValueOf(topic),
+			reflect.ValueOf(s.id),
+			reflect.ValueOf(&data).Elem(),
+		}
+		results, err := reverse.getClient().Invoke("#push", args, settings)
+		if err != nil || !results[0].Bool() {
+			doneMessages(messages, false)
+			t.stopSending(s)
+			service.offlineIf(t, s, func(s *subscriber) bool {
+				return s.native == reverse
+			})
+			return
+		}
+		t.delivered(s, len(messages))
+		doneMessages(messages, true)
+	}
+}
+
+// enqueue the message to the subscriber, the message waits behind the
+// blocked messages if the queue is full and the overflow policy is
+// OverflowBlock.
+func (service *baseService) enqueue(
+	t *topic, topic string, id string, m pushMessage) {
+	t.Lock()
+	s := t.subscribers[id]
+	if s == nil {
+		t.Unlock()
+		m.done(false)
+		return
+	}
+	var dropped []pushMessage
+	if len(s.queue) >= t.options.QueueSize || len(s.blocked) > 0 {
+		switch t.options.Overflow {
+		case OverflowDropOldest:
+			dropped = s.queue[:1]
+			s.queue = s.queue[1:]
+			s.dropped++
+		case OverflowDropNewest:
+			// the client detects the gap by the prev of the next message
+			s.lastSeq = m.seq
+			s.dropped++
+			t.Unlock()
+			m.done(false)
+			return
+		case OverflowDisconnect:
+			t.Unlock()
+			service.offline(t, s)
+			m.done(false)
+			return
+		default:
+			s.blocked = append(s.blocked, m)
+			t.Unlock()
+			return
+		}
+	}
+	s.append(m)
+	send := s.native != nil && !s.sending
+	if send {
+		s.sending = true
+	}
+	s.notify()
+	t.Unlock()
+	doneMessages(dropped, false)
+	if send {
+		go service.sendNative(t, topic, s)
+	}
+}
+
+func (service *baseService) unicast(
+	t *topic, topic string, id string, seq int64,
+	result interface{}, callback func(bool)) {
+	m := pushMessage{seq: seq, data: result, callback: callback}
+	service.enqueue(t, topic, id, m)
+}
+
+// PushBroker returns the push broker of the service
+func (service *baseService) PushBroker() PushBroker {
+	service.topicLock.RLock()
+	defer service.topicLock.RUnlock()
+	return service.broker
+}
+
+// SetPushBroker sets the push broker of the service, the messages pushed by
+// the service are published by the broker, so they reach the subscribers on
+// all of the nodes attached to it. The default is a MemoryBroker with only
+// this service.
+func (service *baseService) SetPushBroker(broker PushBroker) Service {
+	service.topicLock.Lock()
+	old := service.broker
+	service.broker = broker
+	service.topicLock.Unlock()
+	if old != nil {
+		old.Unsubscribe(service.node)
+	}
+	broker.Subscribe(service.node)
+	return service
+}
+
+// QueueStats returns the queue metrics of the subscribers of the topic on
+// this node
+func (service *baseService) QueueStats(topic string) []QueueStats {
+	if t := service.findTopic(topic); t != nil {
+		return t.stats()
+	}
+	return nil
+}
+
+// Subscribers returns the presence of the subscribers of the topic on this
+// node, the topic can be a pattern subscribed by the clients.
+func (service *baseService) Subscribers(topic string) []SubscriberInfo {
+	if t := service.findTopic(topic); t != nil {
+		return t.presence()
+	}
+	return nil
+}
+
+// IDList returns the push client id list on all of the nodes
 func (service *baseService) IDList(topic string) []string {
-	return service.getTopic(topic).idlist()
+	service.findTopic(topic)
+	return service.PushBroker().Presence(topic, false)
 }
 
-// Exist returns true if the client id exist.
+// Exist returns true if the client id exist on any node.
 func (service *baseService) Exist(topic string, id string) bool {
-	return service.getTopic(topic).exist(id)
+	if t := service.findTopic(topic); t != nil && t.exist(id) {
+		return true
+	}
+	return containsID(service.PushBroker().Presence(topic, false), id)
 }
 
-// Push result to clients
-func (service *baseService) Push(topic string, result interface{}, id ...string) {
-	t := service.getTopic(topic)
-	n := len(id)
-	if n == 0 {
-		id = t.idlist()
-		n = len(id)
-		if n == 0 {
-			return
+// MatchIDList returns the id list of the clients which subscribe the topic
+// or the patterns matching it on all of the nodes.
+func (service *baseService) MatchIDList(topic string) []string {
+	service.matchTopics(topic)
+	return service.PushBroker().Presence(topic, true)
+}
+
+// MatchExist returns true if the client id subscribes the topic or a
+// pattern matching it on any node.
+func (service *baseService) MatchExist(topic string, id string) bool {
+	for _, t := range service.matchTopics(topic) {
+		if t.exist(id) {
+			return true
 		}
 	}
-	for i := 0; i < n; i++ {
-		service.unicast(t, topic, id[i], result, nil)
+	return containsID(service.PushBroker().Presence(topic, true), id)
+}
+
+func containsID(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Push result to clients, the clients subscribing the patterns matching the
+// topic receive it too.
+func (service *baseService) Push(topic string, result interface{}, id ...string) {
+	service.matchTopics(topic)
+	if len(id) == 0 {
+		id = nil
 	}
+	service.PushBroker().Publish(topic, id, result, nil)
 }
 
 // Broadcast push result to all clients
 func (service *baseService) Broadcast(
 	topic string, result interface{}, callback func([]string)) {
-	service.Multicast(topic, service.IDList(topic), result, callback)
+	service.matchTopics(topic)
+	service.PushBroker().Publish(topic, nil, result, callback)
 }
 
 // Multicast result to the specified clients
 func (service *baseService) Multicast(
 	topic string, ids []string, result interface{}, callback func([]string)) {
-	t := service.getTopic(topic)
-	m := 0
-	n := len(ids)
+	service.matchTopics(topic)
+	if len(ids) == 0 {
+		if callback != nil {
+			callback(nil)
+		}
+		return
+	}
+	service.PushBroker().Publish(topic, ids, result, callback)
+}
+
+// Unicast result to then specified client
+func (service *baseService) Unicast(
+	topic string, id string, result interface{}, callback func(bool)) {
+	service.Multicast(topic, []string{id}, result, func(sended []string) {
+		if callback != nil {
+			callback(len(sended) > 0)
+		}
+	})
+}
+
+// pushNode attaches the service to its push broker
+type pushNode struct {
+	service *baseService
+}
+
+// Deliver the message published by the broker to the subscribers of the
+// service
+func (node *pushNode) Deliver(
+	topic string, ids []string, result interface{}, callback func([]string)) {
+	var targets []pushTarget
+	for _, t := range node.service.subscribedTopics(topic) {
+		data := t.wrap(topic, result)
+		seq := t.publish(data, ids)
+		targetIDs := ids
+		if ids == nil {
+			targetIDs = t.idlist()
+		}
+		for _, id := range targetIDs {
+			targets = append(targets, pushTarget{t, id, seq})
+		}
+	}
+	node.service.multicast(targets, topic, result, callback)
+}
+
+// Presence returns the ids of the subscribers of the service
+func (node *pushNode) Presence(topic string, match bool) []string {
+	var ids idSet
+	for _, t := range node.service.subscribedTopics(topic) {
+		if match || t.name == topic {
+			ids.add(t.idlist())
+		}
+	}
+	return ids.list
+}
+
+// pushTarget is a subscriber to push the message
+type pushTarget struct {
+	t   *topic
+	id  string
+	seq int64
+}
+
+// multicast calls callback with the ids which the message is delivered to,
+// callback can be nil.
+func (service *baseService) multicast(
+	targets []pushTarget, topic string,
+	result interface{}, callback func([]string)) {
+	if callback == nil {
+		for _, target := range targets {
+			data := target.t.wrap(topic, result)
+			service.unicast(target.t, target.t.name, target.id, target.seq, data, nil)
+		}
+		return
+	}
+	n := len(targets)
 	if n == 0 {
 		callback(nil)
 		return
 	}
+	var m int32
 	sid := make(chan string)
 	go func() {
 		sended := make([]string, 0, n)
+		found := make(map[string]bool, n)
 		for id := range sid {
-			sended = append(sended, id)
+			if !found[id] {
+				found[id] = true
+				sended = append(sended, id)
+			}
 		}
 		callback(sended)
 	}()
-	for i := 0; i < n; i++ {
-		id := ids[i]
-		service.unicast(t, topic, id, result, func(ok bool) {
+	for _, target := range targets {
+		id := target.id
+		data := target.t.wrap(topic, result)
+		service.unicast(target.t, target.t.name, id, target.seq, data, func(ok bool) {
 			if ok {
 				sid <- id
 			}
-			m++
-			if m == n {
+			if int(atomic.AddInt32(&m, 1)) == n {
 				close(sid)
 			}
 		})
 	}
 }
-
-// Unicast result to then specified client
-func (service *baseService) Unicast(
-	topic string, id string, result interface{}, callback func(bool)) {
-	service.unicast(service.getTopic(topic), topic, id, result, callback)
-}
@@ -0,0 +1,178 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/batch.go                                           *
+ *                                                        *
+ * hprose batch invocation for Go.                        *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"fmt"
+	"reflect"
+
+	hio "github.com/hprose/hprose-golang/io"
+)
+
+// BatchCall is a call queued in the Batch
+type BatchCall struct {
+	Name     string
+	Args     []reflect.Value
+	Settings InvokeSettings
+	Results  []reflect.Value
+	Err      error
+}
+
+// Batch queues several calls and sends them to the server in one request.
+//
+// Only ByRef, Mode, JSONCompatible and ResultTypes of the call settings are
+// used, the other settings are specified for the whole batch when it is
+// invoked. Raw and RawWithEndTag result modes are not supported.
+//
+// The batch request goes through the filters and the filter handlers of the
+// client, but not the invoke handlers.
+type Batch struct {
+	client *baseClient
+	calls  []*BatchCall
+}
+
+// Batch returns a new batch of this client
+func (client *baseClient) Batch() *Batch {
+	return &Batch{client: client}
+}
+
+// Add a call to the batch, settings can be nil
+func (batch *Batch) Add(
+	name string, args []reflect.Value, settings *InvokeSettings) *BatchCall {
+	call := &BatchCall{Name: name, Args: args}
+	if settings != nil {
+		if settings.Mode == Raw || settings.Mode == RawWithEndTag {
+			panic("batch doesn't support raw result mode")
+		}
+		call.Settings = *settings
+	}
+	batch.calls = append(batch.calls, call)
+	return call
+}
+
+// Calls returns the queued calls
+func (batch *Batch) Calls() []*BatchCall {
+	return batch.calls
+}
+
+// Invoke sends all the queued calls in one request.
+// The returned error is the error of the request, the result and the error
+// of every call are stored in its Results and Err.
+func (batch *Batch) Invoke(settings *InvokeSettings) error {
+	return batch.InvokeContext(gocontext.Background(), settings)
+}
+
+// InvokeContext is same as Invoke but with a context.Context
+func (batch *Batch) InvokeContext(
+	ctx gocontext.Context, settings *InvokeSettings) (err error) {
+	if len(batch.calls) == 0 {
+		return nil
+	}
+	client := batch.client
+	context := client.getClientContext(settings)
+	context.setContext(ctx)
+	defer func() {
+		if err != nil {
+			for _, call := range batch.calls {
+				call.Results = nil
+				call.Err = err
+			}
+		}
+		context.ctx = nil
+		client.contextPool.Put(context)
+	}()
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	request := batch.encode(context.Simple, context.RemoteError)
+	response, err := client.sendRequest(request, context)
+	if err != nil || context.Oneway {
+		return err
+	}
+	return batch.decode(response)
+}
+
+func (batch *Batch) encode(simple bool, remoteError bool) []byte {
+	writer := hio.NewWriter(simple)
+	if remoteError {
+		writer.WriteByte(hio.TagError)
+	}
+	for _, call := range batch.calls {
+		writer.Reset()
+		writer.WriteByte(hio.TagCall)
+		writer.WriteString(call.Name)
+		if len(call.Args) > 0 || call.Settings.ByRef {
+			writer.Reset()
+			writer.WriteSlice(call.Args)
+			if call.Settings.ByRef {
+				writer.WriteBool(true)
+			}
+		}
+	}
+	writer.WriteByte(hio.TagEnd)
+	return writer.Bytes()
+}
+
+func (batch *Batch) decode(data []byte) (err error) {
+	n := len(data)
+	if n == 0 || data[n-1] != hio.TagEnd {
+		return fmt.Errorf("Wrong Response: \r\n%s", data)
+	}
+	reader := defaultReaderPool.acquireReader(data)
+	defer defaultReaderPool.releaseReader(reader)
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+		}
+	}()
+	tag, _ := reader.ReadByte()
+	for i, call := range batch.calls {
+		if tag == hio.TagEnd && i == 1 && batch.calls[0].Err != nil {
+			// the whole request is failed
+			return batch.calls[0].Err
+		}
+		reader.Reset()
+		reader.JSONCompatible = call.Settings.JSONCompatible
+		call.Results = nil
+		call.Err = nil
+		switch tag {
+		case hio.TagResult:
+			if call.Settings.Mode == Serialized {
+				call.Results = []reflect.Value{reflect.ValueOf(reader.ReadRaw())}
+			} else {
+				call.Results = readResults(reader, call.Settings.ResultTypes)
+			}
+			tag, _ = reader.ReadByte()
+			if tag == hio.TagArgument {
+				tag = readArgs(reader, call.Args)
+			}
+		case hio.TagError:
+			call.Err = readError(reader)
+			tag, _ = reader.ReadByte()
+		default:
+			return fmt.Errorf("Wrong Response: \r\n%s", data)
+		}
+	}
+	if tag != hio.TagEnd {
+		return fmt.Errorf("Wrong Response: \r\n%s", data)
+	}
+	return nil
+}
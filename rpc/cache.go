@@ -0,0 +1,277 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/cache.go                                           *
+ *                                                        *
+ * hprose response cache for Go.                          *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"container/list"
+	gocontext "context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hprose/hprose-golang/io"
+)
+
+// defaultCacheEntries is the max number of the results cached by a client
+const defaultCacheEntries = 1000
+
+type cacheEntry struct {
+	key     string
+	name    string
+	results [][]byte
+	expires time.Time
+}
+
+type cacheCall struct {
+	done    chan struct{}
+	results [][]byte
+	err     error
+}
+
+// Cache is the response cache of hprose client, it caches the results of
+// the idempotent methods in a size-bounded LRU, the key is the method name
+// plus the serialized arguments. The concurrent identical calls are
+// collapsed into one remote call.
+//
+// A method is cached when it is Idempotent and its TTL is positive, the TTL
+// is the CacheTTL of InvokeSettings (the `cache:"30s"` tag in UseService),
+// or the one set by SetTTL. The ByRef and Oneway calls are never cached.
+// The CacheTTL makes the method idempotent.
+//
+// Every client has a Cache for the methods with CacheTTL, it is returned by
+// Client.Cache. Another Cache can be added by AddInvokeHandler, the methods
+// cached by it are not cached by the Cache of the client again.
+//
+// The calls waiting for an identical call return when their own contexts
+// are done.
+//
+// Usage:
+//
+//	cache := rpc.NewCache(1000)
+//	client.AddInvokeHandler(cache.Handler)
+type Cache struct {
+	maxEntries int
+	ttls       map[string]time.Duration
+	entries    map[string]*list.Element
+	lru        *list.List
+	calls      map[string]*cacheCall
+	locker     sync.Mutex
+}
+
+// NewCache is the constructor of Cache,
+// maxEntries is the max number of the cached results, 0 means no limit.
+func NewCache(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		ttls:       make(map[string]time.Duration),
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		calls:      make(map[string]*cacheCall),
+	}
+}
+
+// SetTTL sets the TTL of the method, it is used when the CacheTTL of
+// InvokeSettings is not set.
+func (cache *Cache) SetTTL(name string, ttl time.Duration) {
+	cache.locker.Lock()
+	cache.ttls[strings.ToLower(name)] = ttl
+	cache.locker.Unlock()
+}
+
+// Len returns the number of the cached results
+func (cache *Cache) Len() int {
+	cache.locker.Lock()
+	defer cache.locker.Unlock()
+	return cache.lru.Len()
+}
+
+func cacheKey(name string, args []reflect.Value) string {
+	writer := io.NewWriter(true)
+	writer.WriteString(strings.ToLower(name))
+	writer.WriteSlice(args)
+	return string(writer.Bytes())
+}
+
+// Invalidate removes the cached result of the method with the args
+func (cache *Cache) Invalidate(name string, args ...interface{}) {
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		in[i] = reflect.ValueOf(arg)
+	}
+	key := cacheKey(name, in)
+	cache.locker.Lock()
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+	}
+	cache.locker.Unlock()
+}
+
+// InvalidateMethod removes all the cached results of the method
+func (cache *Cache) InvalidateMethod(name string) {
+	name = strings.ToLower(name)
+	cache.locker.Lock()
+	for _, element := range cache.entries {
+		if element.Value.(*cacheEntry).name == name {
+			cache.remove(element)
+		}
+	}
+	cache.locker.Unlock()
+}
+
+// Clear removes all the cached results
+func (cache *Cache) Clear() {
+	cache.locker.Lock()
+	cache.entries = make(map[string]*list.Element)
+	cache.lru.Init()
+	cache.locker.Unlock()
+}
+
+// remove must be called with the lock held
+func (cache *Cache) remove(element *list.Element) {
+	cache.lru.Remove(element)
+	delete(cache.entries, element.Value.(*cacheEntry).key)
+}
+
+// get must be called with the lock held
+func (cache *Cache) get(key string) [][]byte {
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		cache.remove(element)
+		return nil
+	}
+	cache.lru.MoveToFront(element)
+	return entry.results
+}
+
+// add must be called with the lock held
+func (cache *Cache) add(
+	key, name string, results [][]byte, ttl time.Duration) {
+	entry := &cacheEntry{key, name, results, time.Now().Add(ttl)}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.lru.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.lru.PushFront(entry)
+	if cache.maxEntries > 0 && cache.lru.Len() > cache.maxEntries {
+		cache.remove(cache.lru.Back())
+	}
+}
+
+func (cache *Cache) ttl(name string, context *ClientContext) time.Duration {
+	if !context.Idempotent || context.ByRef || context.Oneway {
+		return 0
+	}
+	if context.CacheTTL > 0 {
+		return context.CacheTTL
+	}
+	cache.locker.Lock()
+	defer cache.locker.Unlock()
+	return cache.ttls[strings.ToLower(name)]
+}
+
+func serializeResults(results []reflect.Value) [][]byte {
+	data := make([][]byte, len(results))
+	for i, result := range results {
+		writer := io.NewWriter(true)
+		writer.WriteValue(result)
+		data[i] = writer.Bytes()
+	}
+	return data
+}
+
+func unserializeResults(
+	data [][]byte, resultTypes []reflect.Type) []reflect.Value {
+	results := make([]reflect.Value, len(data))
+	for i := range data {
+		var t reflect.Type
+		if i < len(resultTypes) {
+			t = resultTypes[i]
+		} else {
+			t = interfaceType
+		}
+		results[i] = reflect.New(t).Elem()
+		io.NewReader(data[i], true).ReadValue(results[i])
+	}
+	return results
+}
+
+// Handler is the InvokeHandler of Cache
+func (cache *Cache) Handler(
+	name string,
+	args []reflect.Value,
+	context Context,
+	next NextInvokeHandler) (results []reflect.Value, err error) {
+	ctx := context.(*ClientContext)
+	ttl := cache.ttl(name, ctx)
+	if ttl <= 0 {
+		return next(name, args, context)
+	}
+	ctx.cached = true
+	key := cacheKey(name, args)
+	cache.locker.Lock()
+	if data := cache.get(key); data != nil {
+		cache.locker.Unlock()
+		return unserializeResults(data, ctx.ResultTypes), nil
+	}
+	if call, ok := cache.calls[key]; ok {
+		cache.locker.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Context().Done():
+			return nil, ctx.Context().Err()
+		}
+		switch call.err {
+		case nil:
+			return unserializeResults(call.results, ctx.ResultTypes), nil
+		case gocontext.Canceled, gocontext.DeadlineExceeded:
+			// the context of the identical call is done, not this one
+			return next(name, args, context)
+		}
+		return nil, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	cache.calls[key] = call
+	cache.locker.Unlock()
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+			call.err = err
+		}
+		cache.locker.Lock()
+		delete(cache.calls, key)
+		if call.err == nil {
+			cache.add(key, strings.ToLower(name), call.results, ttl)
+		}
+		cache.locker.Unlock()
+		close(call.done)
+	}()
+	results, err = next(name, args, context)
+	call.err = err
+	if err == nil {
+		call.results = serializeResults(results)
+	}
+	return
+}
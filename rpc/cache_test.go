@@ -0,0 +1,111 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/cache_test.go                                      *
+ *                                                        *
+ * hprose response cache test for Go.                     *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestCacheContext(idempotent bool) *ClientContext {
+	context := new(ClientContext)
+	context.Idempotent = idempotent
+	context.CacheTTL = time.Second
+	return context
+}
+
+func TestCache_Idempotent(t *testing.T) {
+	cache := NewCache(0)
+	count := 0
+	next := func(
+		name string, args []reflect.Value,
+		context Context) ([]reflect.Value, error) {
+		count++
+		return []reflect.Value{reflect.ValueOf(count)}, nil
+	}
+	for i := 0; i < 2; i++ {
+		cache.Handler("get", nil, newTestCacheContext(false), next)
+	}
+	if count != 2 || cache.Len() != 0 {
+		t.Error(count, cache.Len())
+	}
+	for i := 0; i < 2; i++ {
+		cache.Handler("get", nil, newTestCacheContext(true), next)
+	}
+	if count != 3 || cache.Len() != 1 {
+		t.Error(count, cache.Len())
+	}
+}
+
+func TestCache_WaiterContext(t *testing.T) {
+	cache := NewCache(0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	next := func(
+		name string, args []reflect.Value,
+		context Context) ([]reflect.Value, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}
+	go cache.Handler("get", nil, newTestCacheContext(true), next)
+	<-started
+	defer close(release)
+	ctx, cancel := gocontext.WithTimeout(
+		gocontext.Background(), 20*time.Millisecond)
+	defer cancel()
+	context := newTestCacheContext(true)
+	context.setContext(ctx)
+	_, err := cache.Handler("get", nil, context, next)
+	if err != gocontext.DeadlineExceeded {
+		t.Error(err)
+	}
+}
+
+func TestCache_Tag(t *testing.T) {
+	count := 0
+	server := NewInProcServer("inproc://cache")
+	server.AddFunction("get", func() int {
+		count++
+		return count
+	}, Options{})
+	server.Handle()
+	defer server.Close()
+	var stub struct {
+		Get func() (int, error) `cache:"1s"`
+	}
+	client := NewInProcClient(server.URI())
+	defer client.Close()
+	client.UseService(&stub)
+	stub.Get()
+	if r, err := stub.Get(); r != 1 || err != nil || client.Cache().Len() != 1 {
+		t.Error("the method with the cache tag is not cached", r, err)
+	}
+	// the results cached by another Cache are not cached by the client
+	cache := NewCache(0)
+	client.AddInvokeHandler(cache.Handler)
+	client.Cache().InvalidateMethod("get")
+	stub.Get()
+	if r, _ := stub.Get(); r != 2 || cache.Len() != 1 || client.Cache().Len() != 0 {
+		t.Error(r, cache.Len(), client.Cache().Len())
+	}
+}
@@ -0,0 +1,243 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/circuit_breaker.go                                 *
+ *                                                        *
+ * hprose circuit breaker for Go.                         *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when the circuit of the service address is open,
+// the request isn't retried after a delay, it is only sent to another
+// address at once when the client can switch to it.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of the circuit
+type CircuitState int
+
+const (
+	// CircuitClosed means the requests are sent normally
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means the requests fail fast with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen means a few trial requests are sent
+	CircuitHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type circuit struct {
+	state       CircuitState
+	requests    int
+	failures    int
+	successes   int
+	trials      int
+	windowStart time.Time
+	openedAt    time.Time
+}
+
+// CircuitBreaker tracks the failure rate of every service address, and
+// opens the circuit of the address when the failure rate reaches the
+// threshold, the requests to this address fail fast with ErrCircuitOpen.
+// After OpenTimeout, a few trial requests are sent to the address, the
+// circuit is closed if all of them succeed, otherwise it is opened again.
+//
+// Usage:
+//
+//	breaker := rpc.NewCircuitBreaker()
+//	client.AddBeforeFilterHandler(breaker.Handler)
+type CircuitBreaker struct {
+	// FailureRate is the failure rate which opens the circuit, from 0 to 1
+	FailureRate float64
+	// MinRequests is the min requests count in the window before the
+	// failure rate is checked
+	MinRequests int
+	// Window is the period of the failure rate statistics
+	Window time.Duration
+	// OpenTimeout is the duration of the open state
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the count of the trial requests in half open state
+	HalfOpenRequests int
+	// IsFailure reports whether err is a failure, the errors which are not
+	// failures are not counted. If it is nil, all errors except the context
+	// cancellation and FilterError are failures
+	IsFailure func(err error) bool
+	circuits  map[string]*circuit
+	locker    sync.Mutex
+}
+
+// NewCircuitBreaker is the constructor of CircuitBreaker
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureRate:      0.5,
+		MinRequests:      10,
+		Window:           10 * time.Second,
+		OpenTimeout:      5 * time.Second,
+		HalfOpenRequests: 1,
+		circuits:         make(map[string]*circuit),
+	}
+}
+
+// State returns the circuit state of the service address
+func (cb *CircuitBreaker) State(uri string) CircuitState {
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+	if c := cb.circuits[uri]; c != nil {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+func (cb *CircuitBreaker) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if cb.IsFailure != nil {
+		return cb.IsFailure(err)
+	}
+	return err != gocontext.Canceled && !isFilterError(err)
+}
+
+func (c *circuit) reset(state CircuitState, now time.Time) {
+	c.state = state
+	c.requests = 0
+	c.failures = 0
+	c.successes = 0
+	c.trials = 0
+	c.windowStart = now
+	c.openedAt = now
+}
+
+// allow must be called with the lock held
+func (cb *CircuitBreaker) allow(
+	c *circuit, now time.Time) (allowed, trial bool, from CircuitState) {
+	from = c.state
+	switch c.state {
+	case CircuitClosed:
+		if now.Sub(c.windowStart) > cb.Window {
+			c.reset(CircuitClosed, now)
+		}
+		return true, false, from
+	case CircuitOpen:
+		if now.Sub(c.openedAt) < cb.OpenTimeout {
+			return false, false, from
+		}
+		c.reset(CircuitHalfOpen, now)
+	}
+	if c.trials < cb.HalfOpenRequests {
+		c.trials++
+		return true, true, from
+	}
+	return false, false, from
+}
+
+// record must be called with the lock held, the errors which are not
+// failures are not counted
+func (cb *CircuitBreaker) record(
+	c *circuit, trial bool, err error, now time.Time) (from CircuitState) {
+	from = c.state
+	failed := cb.isFailure(err)
+	ignored := err != nil && !failed
+	switch c.state {
+	case CircuitClosed:
+		if trial || ignored {
+			return
+		}
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if c.requests >= cb.MinRequests &&
+			float64(c.failures) >= cb.FailureRate*float64(c.requests) {
+			c.reset(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if !trial {
+			return
+		}
+		switch {
+		case ignored:
+			c.trials--
+		case failed:
+			c.reset(CircuitOpen, now)
+		default:
+			c.successes++
+			if c.successes >= cb.HalfOpenRequests {
+				c.reset(CircuitClosed, now)
+			}
+		}
+	}
+	return
+}
+
+func fireCircuitStateChangeEvent(
+	context *ClientContext, uri string, from, to CircuitState) {
+	if from == to {
+		return
+	}
+	if client, ok := context.Client.(*baseClient); ok {
+		if event, ok := client.event.(circuitStateChangeEvent); ok {
+			event.OnCircuitStateChange(uri, from, to)
+		}
+	}
+}
+
+// Handler is the FilterHandler of CircuitBreaker
+func (cb *CircuitBreaker) Handler(
+	request []byte,
+	context Context,
+	next NextFilterHandler) (response []byte, err error) {
+	ctx := context.(*ClientContext)
+	uri := ctx.URI
+	now := time.Now()
+	cb.locker.Lock()
+	c := cb.circuits[uri]
+	if c == nil {
+		c = &circuit{windowStart: now}
+		cb.circuits[uri] = c
+	}
+	allowed, trial, from := cb.allow(c, now)
+	to := c.state
+	cb.locker.Unlock()
+	fireCircuitStateChangeEvent(ctx, uri, from, to)
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+	response, err = next(request, context)
+	now = time.Now()
+	cb.locker.Lock()
+	from = cb.record(c, trial, err, now)
+	to = c.state
+	cb.locker.Unlock()
+	fireCircuitStateChangeEvent(ctx, uri, from, to)
+	return
+}
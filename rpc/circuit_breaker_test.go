@@ -0,0 +1,89 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/circuit_breaker_test.go                            *
+ *                                                        *
+ * hprose circuit breaker test for Go.                    *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCircuitServer(uri string) *InProcServer {
+	server := NewInProcServer(uri)
+	server.AddFunction("hello", func() string { return "hi" }, Options{})
+	server.Handle()
+	return server
+}
+
+// openCircuit opens the circuit of the current address of the client
+func openCircuit(client *InProcClient) *CircuitBreaker {
+	breaker := NewCircuitBreaker()
+	breaker.OpenTimeout = time.Minute
+	breaker.circuits[client.URI()] = &circuit{
+		state: CircuitOpen, openedAt: time.Now(),
+	}
+	client.AddBeforeFilterHandler(breaker.Handler)
+	return breaker
+}
+
+func TestCircuitBreaker_OpenNotDelayed(t *testing.T) {
+	server := newTestCircuitServer("inproc://circuit")
+	defer server.Close()
+	for _, policy := range []*RetryPolicy{nil, {BaseDelay: time.Second}} {
+		client := NewInProcClient(server.URI())
+		client.SetRetryPolicy(policy)
+		openCircuit(client)
+		var stub struct {
+			Hello func() (string, error) `idempotent:"true" retry:"3"`
+		}
+		client.UseService(&stub)
+		start := time.Now()
+		if _, err := stub.Hello(); err != ErrCircuitOpen {
+			t.Error(err)
+		}
+		if d := time.Since(start); d > 200*time.Millisecond {
+			t.Error("the request to the open circuit is delayed", d)
+		}
+		client.Close()
+	}
+}
+
+func TestCircuitBreaker_OpenFailswitch(t *testing.T) {
+	server1 := newTestCircuitServer("inproc://circuit1")
+	defer server1.Close()
+	server2 := newTestCircuitServer("inproc://circuit2")
+	defer server2.Close()
+	for _, policy := range []*RetryPolicy{nil, {BaseDelay: time.Second}} {
+		client := NewInProcClient(server1.URI(), server2.URI())
+		client.SetRetryPolicy(policy)
+		openCircuit(client)
+		var stub struct {
+			Hello func() (string, error) `idempotent:"true" failswitch:"true" retry:"3"`
+		}
+		client.UseService(&stub)
+		start := time.Now()
+		if r, err := stub.Hello(); err != nil || r != "hi" {
+			t.Error(r, err)
+		}
+		if d := time.Since(start); d > 200*time.Millisecond {
+			t.Error("the request to the next address is delayed", d)
+		}
+		client.Close()
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose rpc client for Go.                              *
  *                                                        *
- * LastModified: Oct 27, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,12 +20,13 @@
 package rpc
 
 import (
+	gocontext "context"
 	"crypto/tls"
 	"errors"
 	"net/url"
 	"reflect"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -40,8 +41,21 @@ type InvokeSettings struct {
 	Retry          int
 	Mode           ResultMode
 	Timeout        time.Duration
-	ResultTypes    []reflect.Type
-	userData       map[string]interface{}
+	RetryPolicy    *RetryPolicy
+	HedgingPolicy  *HedgingPolicy
+	// CacheTTL is the time to live of the cached results, it is the
+	// `cache:"30s"` tag in UseService. The method is idempotent if it is
+	// positive, and its results are cached by the Cache of the client
+	// unless another Cache added by AddInvokeHandler caches them.
+	CacheTTL    time.Duration
+	ResultTypes []reflect.Type
+	// RemoteError requests the service to send the errors as RemoteError
+	// with their codes and data, the service must support it
+	RemoteError bool
+	// Metadata is attached to the presence of the subscriber when the
+	// settings are used to subscribe a push topic
+	Metadata map[string]interface{}
+	userData map[string]interface{}
 }
 
 // SetUserData on InvokeSettings
@@ -59,10 +73,18 @@ type Client interface {
 	SetURI(uri string)
 	URIList() []string
 	SetURIList(uriList []string)
+	Balancer() Balancer
+	SetBalancer(balancer Balancer)
 	TLSClientConfig() *tls.Config
 	SetTLSClientConfig(config *tls.Config)
 	Retry() int
 	SetRetry(value int)
+	RetryPolicy() *RetryPolicy
+	SetRetryPolicy(policy *RetryPolicy)
+	HedgingPolicy() *HedgingPolicy
+	SetHedgingPolicy(policy *HedgingPolicy)
+	Cache() *Cache
+	SetCache(cache *Cache)
 	Timeout() time.Duration
 	SetTimeout(value time.Duration)
 	Failround() int
@@ -79,11 +101,16 @@ type Client interface {
 	SetUserData(userdata map[string]interface{}) Client
 	UseService(remoteService interface{}, namespace ...string)
 	Invoke(string, []reflect.Value, *InvokeSettings) ([]reflect.Value, error)
+	InvokeContext(gocontext.Context, string, []reflect.Value, *InvokeSettings) ([]reflect.Value, error)
 	Go(string, []reflect.Value, *InvokeSettings, Callback)
+	Batch() *Batch
 	Close()
 	AutoID() (string, error)
 	ID() string
 	Subscribe(name string, id string, settings *InvokeSettings, callback interface{}) (err error)
+	SubscribeFrom(name string, id string, seq int64, settings *InvokeSettings, callback interface{}) (err error)
+	SubscribeChan(name string, id string, settings *InvokeSettings, chanPtr interface{}) (*Subscription, error)
+	LastSeq(name string, id string) int64
 	Unsubscribe(name string, id ...string)
 	IsSubscribed(name string) bool
 	SubscribedList() []string
@@ -95,25 +122,79 @@ type ClientContext struct {
 	InvokeSettings
 	Retried int
 	Client  Client
+	URI     string
+	ctx     gocontext.Context
+	cached  bool
 }
 
-// NewClient is the constructor of Client
+// Context returns the context.Context of this invocation,
+// it is never nil.
+func (context *ClientContext) Context() gocontext.Context {
+	if context.ctx == nil {
+		return gocontext.Background()
+	}
+	return context.ctx
+}
+
+// clone returns a copy of the context for a hedged request
+func (context *ClientContext) clone(ctx gocontext.Context) *ClientContext {
+	c := new(ClientContext)
+	c.initBaseContext()
+	for k, v := range context.UserData() {
+		c.SetInterface(k, v)
+	}
+	c.InvokeSettings = context.InvokeSettings
+	c.Retried = context.Retried
+	c.Client = context.Client
+	c.URI = context.URI
+	c.ctx = ctx
+	return c
+}
+
+func (context *ClientContext) setContext(ctx gocontext.Context) {
+	context.ctx = ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout := deadline.Sub(time.Now()); timeout < context.Timeout {
+			context.Timeout = timeout
+		}
+	}
+}
+
+// NewClient is the constructor of Client, the type of the client is
+// selected by the scheme of uri, the schemes are registered by
+// RegisterClientFactory.
 func NewClient(uri ...string) Client {
-	return clientFactories[checkAddresses(uri, allSchemes)](uri...)
+	return getClientFactory(checkAddresses(uri, nil))(uri...)
 }
 
 // UseFastHTTPClient as the default http client
 func UseFastHTTPClient() {
-	registerClientFactory("http", newFastHTTPClient)
-	registerClientFactory("https", newFastHTTPClient)
+	RegisterClientFactory("http", newFastHTTPClient)
+	RegisterClientFactory("https", newFastHTTPClient)
 }
 
 var httpSchemes = []string{"http", "https"}
 var tcpSchemes = []string{"tcp", "tcp4", "tcp6"}
 var unixSchemes = []string{"unix"}
 var websocketSchemes = []string{"ws", "wss"}
-var allSchemes = []string{"http", "https", "tcp", "tcp4", "tcp6", "unix", "ws", "wss"}
+var inprocSchemes = []string{"inproc"}
+var stdioSchemes = []string{"stdio"}
 
+func supportScheme(schemes []string, scheme string) bool {
+	if schemes == nil {
+		return getClientFactory(scheme) != nil
+	}
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddresses returns the scheme of uriList, it panics if the addresses
+// use different schemes, or the scheme is not in schemes. If schemes is nil,
+// the scheme is checked by the registered client factories.
 func checkAddresses(uriList []string, schemes []string) (scheme string) {
 	count := len(uriList)
 	if count < 1 {
@@ -124,7 +205,7 @@ func checkAddresses(uriList []string, schemes []string) (scheme string) {
 		panic(err)
 	}
 	scheme = u.Scheme
-	if sort.SearchStrings(schemes, scheme) == len(schemes) {
+	if !supportScheme(schemes, scheme) {
 		panic(errors.New("This client desn't support " + scheme + " scheme."))
 	}
 	for i := 1; i < count; i++ {
@@ -139,19 +220,39 @@ func checkAddresses(uriList []string, schemes []string) (scheme string) {
 	return
 }
 
-var clientFactories = make(map[string]func(...string) Client)
+// ClientFactory creates a Client for the service addresses
+type ClientFactory func(uri ...string) Client
+
+var clientFactories = make(map[string]ClientFactory)
+var clientFactoriesLock sync.RWMutex
 
-func registerClientFactory(scheme string, newClient func(...string) Client) {
+// RegisterClientFactory registers the client factory for scheme, then
+// NewClient creates the client by it for the addresses of the scheme.
+//
+// The client created by the factory must check the addresses with the same
+// scheme in SetURIList. RegisterTransport is an easier way to add a scheme
+// if you only need to implement how to send the requests.
+func RegisterClientFactory(scheme string, newClient ClientFactory) {
+	clientFactoriesLock.Lock()
 	clientFactories[strings.ToLower(scheme)] = newClient
+	clientFactoriesLock.Unlock()
+}
+
+func getClientFactory(scheme string) ClientFactory {
+	clientFactoriesLock.RLock()
+	defer clientFactoriesLock.RUnlock()
+	return clientFactories[scheme]
 }
 
 func init() {
-	registerClientFactory("http", newHTTPClient)
-	registerClientFactory("https", newHTTPClient)
-	registerClientFactory("tcp", newTCPClient)
-	registerClientFactory("tcp4", newTCPClient)
-	registerClientFactory("tcp6", newTCPClient)
-	registerClientFactory("unix", newUnixClient)
-	registerClientFactory("ws", newWebSocketClient)
-	registerClientFactory("wss", newWebSocketClient)
+	RegisterClientFactory("http", newHTTPClient)
+	RegisterClientFactory("https", newHTTPClient)
+	RegisterClientFactory("tcp", newTCPClient)
+	RegisterClientFactory("tcp4", newTCPClient)
+	RegisterClientFactory("tcp6", newTCPClient)
+	RegisterClientFactory("unix", newUnixClient)
+	RegisterClientFactory("ws", newWebSocketClient)
+	RegisterClientFactory("wss", newWebSocketClient)
+	RegisterClientFactory("inproc", newInProcClient)
+	RegisterClientFactory("stdio", newStdioClient)
 }
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose client event for Go.                            *
  *                                                        *
- * LastModified: Sep 23, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -29,3 +29,12 @@ type onErrorEvent interface {
 type onFailswitchEvent interface {
 	OnFailswitch(Client)
 }
+
+type circuitStateChangeEvent interface {
+	OnCircuitStateChange(uri string, from, to CircuitState)
+}
+
+// pushGapEvent reports the messages between lastSeq and seq may be lost
+type pushGapEvent interface {
+	OnPushGap(topic string, id string, lastSeq int64, seq int64)
+}
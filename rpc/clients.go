@@ -12,7 +12,7 @@
  *                                                        *
  * hprose clients for Go.                                 *
  *                                                        *
- * LastModified: Sep 21, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -23,8 +23,12 @@ package rpc
 type Clients interface {
 	IDList(topic string) []string
 	Exist(topic string, id string) bool
+	MatchIDList(topic string) []string
+	MatchExist(topic string, id string) bool
 	Push(topic string, result interface{}, id ...string)
 	Broadcast(topic string, result interface{}, callback func([]string))
 	Multicast(topic string, ids []string, result interface{}, callback func([]string))
 	Unicast(topic string, id string, result interface{}, callback func(bool))
+	QueueStats(topic string) []QueueStats
+	Subscribers(topic string) []SubscriberInfo
 }
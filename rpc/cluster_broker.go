@@ -0,0 +1,265 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/cluster_broker.go                                  *
+ *                                                        *
+ * hprose cluster push broker for Go.                     *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClusterBroker federates the push of the service nodes behind a load
+// balancer. Every node runs a ClusterBroker which links the brokers of the
+// other nodes over hprose tcp, so the messages pushed on a node reach the
+// subscribers on all of the nodes, and IDList aggregates the presence of
+// all of the nodes.
+//
+// The sequence numbers of the messages are assigned by every node, and the
+// pushed results are serialized between the nodes, so they should be the
+// types which can be unserialized without registration.
+//
+// The callback of Publish receives the ids on the other nodes when the
+// message is queued to them, it doesn't wait for them to receive it. The
+// errors of the requests to the other brokers are reported by the OnError
+// of the event set by SetEvent, the name is the address of the broker.
+//
+// Usage:
+//
+//	broker := rpc.NewClusterBroker("tcp://10.0.0.1:4322",
+//		"tcp://10.0.0.2:4322", "tcp://10.0.0.3:4322")
+//	if err := broker.Start(); err != nil {
+//		panic(err)
+//	}
+//	defer broker.Close()
+//	service.SetPushBroker(broker)
+type ClusterBroker struct {
+	MemoryBroker
+	// Timeout is the timeout of the requests to the other brokers, the
+	// default is 30 seconds.
+	Timeout time.Duration
+	server  *TCPServer
+	peers   map[string]*TCPClient
+	event   ClientEvent
+	locker  sync.RWMutex
+}
+
+// NewClusterBroker is the constructor of ClusterBroker, uri is the address
+// which this broker listens on, peers are the addresses of the other
+// brokers, this broker is skipped if it is in peers.
+func NewClusterBroker(uri string, peers ...string) *ClusterBroker {
+	broker := new(ClusterBroker)
+	broker.Timeout = 30 * time.Second
+	broker.server = NewTCPServer(uri)
+	broker.server.AddFunction("deliver", broker.deliver, Options{Simple: true})
+	broker.server.AddFunction("presence", broker.MemoryBroker.Presence, Options{Simple: true})
+	broker.peers = make(map[string]*TCPClient)
+	broker.SetPeers(peers)
+	return broker
+}
+
+// URI returns the real address of this broker
+func (broker *ClusterBroker) URI() string {
+	return broker.server.URI()
+}
+
+// Start listening for the other brokers
+func (broker *ClusterBroker) Start() error {
+	return broker.server.Handle()
+}
+
+// Close the links to the other brokers
+func (broker *ClusterBroker) Close() {
+	broker.server.Close()
+	broker.server.closeConns()
+	broker.SetPeers(nil)
+}
+
+// SetEvent sets the event of the links to the other brokers
+func (broker *ClusterBroker) SetEvent(event ClientEvent) {
+	broker.locker.Lock()
+	defer broker.locker.Unlock()
+	broker.event = event
+	for _, client := range broker.peers {
+		client.SetEvent(event)
+	}
+}
+
+// Peers returns the addresses of the other brokers
+func (broker *ClusterBroker) Peers() []string {
+	broker.locker.RLock()
+	defer broker.locker.RUnlock()
+	peers := make([]string, 0, len(broker.peers))
+	for uri := range broker.peers {
+		peers = append(peers, uri)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// SetPeers sets the addresses of the other brokers, the links to the
+// removed brokers are closed.
+func (broker *ClusterBroker) SetPeers(peers []string) {
+	uris := make(map[string]bool, len(peers))
+	for _, uri := range peers {
+		if uri != broker.server.uri {
+			uris[uri] = true
+		}
+	}
+	broker.locker.Lock()
+	defer broker.locker.Unlock()
+	for uri, client := range broker.peers {
+		if !uris[uri] {
+			client.Close()
+			delete(broker.peers, uri)
+		}
+	}
+	for uri := range uris {
+		if broker.peers[uri] == nil {
+			client := NewTCPClient(uri)
+			client.SetFullDuplex(true)
+			client.SetEvent(broker.event)
+			broker.peers[uri] = client
+		}
+	}
+}
+
+func (broker *ClusterBroker) getPeers() []*TCPClient {
+	broker.locker.RLock()
+	defer broker.locker.RUnlock()
+	peers := make([]*TCPClient, 0, len(broker.peers))
+	for _, client := range broker.peers {
+		peers = append(peers, client)
+	}
+	return peers
+}
+
+// Publish the message to the subscribers of the topic on all of the nodes
+// of the cluster, the nodes which can't be reached are skipped and reported.
+func (broker *ClusterBroker) Publish(
+	topic string, ids []string, result interface{}, callback func([]string)) {
+	peers := broker.getPeers()
+	done := joinCallback(len(peers)+1, callback)
+	broker.MemoryBroker.Publish(topic, ids, result, done)
+	for _, peer := range peers {
+		go broker.publishTo(peer, topic, ids, result, done)
+	}
+}
+
+func (broker *ClusterBroker) publishTo(
+	peer *TCPClient, topic string, ids []string,
+	result interface{}, callback func([]string)) {
+	args := []reflect.Value{
+		reflect.ValueOf(topic),
+		reflect.ValueOf(ids),
+		reflect.ValueOf(&result).Elem(),
+		reflect.ValueOf(callback != nil),
+	}
+	settings := &InvokeSettings{
+		Simple:      true,
+		Timeout:     broker.Timeout,
+		ResultTypes: []reflect.Type{stringSliceType},
+	}
+	results, err := peer.Invoke("deliver", args, settings)
+	if err != nil {
+		peer.fireErrorEvent(peer.URI(), err)
+	}
+	if callback == nil {
+		return
+	}
+	if err != nil {
+		callback(nil)
+		return
+	}
+	callback(results[0].Interface().([]string))
+}
+
+// deliver the message published by the other broker to the nodes attached
+// to this broker, it returns the ids which the message is queued to if want
+// is true, it doesn't wait for the subscribers to receive the message, so
+// the slow subscribers don't make the request time out.
+func (broker *ClusterBroker) deliver(
+	topic string, ids []string, result interface{}, want bool) []string {
+	var queued []string
+	if want {
+		queued = broker.queuedIDs(topic, ids)
+	}
+	broker.MemoryBroker.Publish(topic, ids, result, nil)
+	return queued
+}
+
+// queuedIDs returns the ids of the subscribers on the nodes attached to this
+// broker which the message pushed to the ids is queued to, ids is nil if
+// the message is pushed to all of the subscribers.
+func (broker *ClusterBroker) queuedIDs(topic string, ids []string) []string {
+	present := broker.MemoryBroker.Presence(topic, true)
+	if ids == nil {
+		if present == nil {
+			return []string{}
+		}
+		return present
+	}
+	found := make(map[string]bool, len(present))
+	for _, id := range present {
+		found[id] = true
+	}
+	var queued idSet
+	queued.list = []string{}
+	for _, id := range ids {
+		if found[id] {
+			queued.add([]string{id})
+		}
+	}
+	return queued.list
+}
+
+// Presence returns the ids of the subscribers of the topic on all of the
+// nodes of the cluster, the nodes which can't be reached are skipped and
+// reported.
+func (broker *ClusterBroker) Presence(topic string, match bool) []string {
+	var ids idSet
+	ids.add(broker.MemoryBroker.Presence(topic, match))
+	peers := broker.getPeers()
+	presences := make([][]string, len(peers))
+	var wg sync.WaitGroup
+	wg.Add(len(peers))
+	for i, peer := range peers {
+		go func(i int, peer *TCPClient) {
+			defer wg.Done()
+			args := []reflect.Value{reflect.ValueOf(topic), reflect.ValueOf(match)}
+			settings := &InvokeSettings{
+				Simple:      true,
+				Timeout:     broker.Timeout,
+				ResultTypes: []reflect.Type{stringSliceType},
+			}
+			results, err := peer.Invoke("presence", args, settings)
+			if err != nil {
+				peer.fireErrorEvent(peer.URI(), err)
+				return
+			}
+			presences[i] = results[0].Interface().([]string)
+		}(i, peer)
+	}
+	wg.Wait()
+	for _, presence := range presences {
+		ids.add(presence)
+	}
+	return ids.list
+}
@@ -0,0 +1,92 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/cluster_broker_test.go                             *
+ *                                                        *
+ * hprose cluster push broker test for Go.                *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestClusterBroker(t *testing.T) *ClusterBroker {
+	broker := NewClusterBroker("tcp://127.0.0.1:0")
+	if err := broker.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return broker
+}
+
+func TestClusterBroker_SlowSubscriber(t *testing.T) {
+	b1 := newTestClusterBroker(t)
+	defer b1.Close()
+	b2 := newTestClusterBroker(t)
+	defer b2.Close()
+	b1.Timeout = 200 * time.Millisecond
+	b1.SetPeers([]string{b2.URI()})
+	s1 := NewHTTPService()
+	s1.Publish("news", time.Second, time.Second)
+	s1.SetPushBroker(b1)
+	s2 := NewHTTPService()
+	s2.Publish("news", time.Second, time.Second)
+	s2.SetPushBroker(b2)
+	// c1 never polls the message
+	s2.resolveTopic("news").beginPoll("c1", true, 0, nil, nil)
+	done := make(chan []string, 1)
+	s1.Broadcast("news", "hello", func(ids []string) { done <- ids })
+	select {
+	case ids := <-done:
+		if !reflect.DeepEqual(ids, []string{"c1"}) {
+			t.Error(ids)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the callback is not called")
+	}
+	if stats := s2.QueueStats("news"); stats[0].Length != 1 {
+		t.Error(stats)
+	}
+}
+
+type testChanNameEvent chan string
+
+func (e testChanNameEvent) OnError(name string, err error) {
+	e <- name
+}
+
+func TestClusterBroker_PeerError(t *testing.T) {
+	broker := newTestClusterBroker(t)
+	defer broker.Close()
+	broker.Timeout = 200 * time.Millisecond
+	event := make(testChanNameEvent, 2)
+	broker.SetEvent(event)
+	peer := "tcp://127.0.0.1:1"
+	broker.SetPeers([]string{peer})
+	broker.Publish("news", nil, "hello", nil)
+	broker.Presence("news", false)
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-event:
+			if name != peer {
+				t.Error(name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the error of the peer is not reported")
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/dns_resolver.go                                    *
+ *                                                        *
+ * hprose dns srv resolver for Go.                        *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNSResolver resolves the service addresses from the DNS SRV records of
+// a name. The target address is "dns+srv://nameserver/name", such as:
+//
+//	dns+srv://127.0.0.1:53/_hprose._tcp.example.com?scheme=tcp&interval=30s
+//
+// The nameserver can be omitted to use the system resolver, the scheme
+// of the resolved addresses is tcp by default, and the records are
+// reloaded every 30 seconds by default.
+type DNSResolver struct {
+	*pollingResolver
+	Name       string
+	Scheme     string
+	Nameserver string
+	Timeout    time.Duration
+	resolver   *net.Resolver
+}
+
+// NewDNSResolver is the constructor of DNSResolver
+func NewDNSResolver(
+	nameserver, name, scheme string,
+	interval time.Duration) *DNSResolver {
+	r := &DNSResolver{
+		Name:       name,
+		Scheme:     scheme,
+		Nameserver: nameserver,
+		Timeout:    5 * time.Second,
+		resolver:   net.DefaultResolver,
+	}
+	if nameserver != "" {
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(
+				ctx gocontext.Context,
+				network, address string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, nameserver)
+			},
+		}
+	}
+	r.pollingResolver = newPollingResolver(r.resolve, interval)
+	return r
+}
+
+func newDNSResolver(target *url.URL) (Resolver, error) {
+	interval, err := getInterval(target, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	nameserver := target.Host
+	if nameserver != "" {
+		if _, _, err := net.SplitHostPort(nameserver); err != nil {
+			nameserver = net.JoinHostPort(nameserver, "53")
+		}
+	}
+	scheme := target.Query().Get("scheme")
+	if scheme == "" {
+		scheme = "tcp"
+	}
+	name := strings.TrimPrefix(target.Path, "/")
+	return NewDNSResolver(nameserver, name, scheme, interval), nil
+}
+
+func (r *DNSResolver) resolve() ([]string, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), r.Timeout)
+	defer cancel()
+	_, records, err := r.resolver.LookupSRV(ctx, "", "", r.Name)
+	if err != nil {
+		return nil, err
+	}
+	uriList := make([]string, 0, len(records))
+	for _, srv := range records {
+		host := strings.TrimSuffix(srv.Target, ".")
+		port := strconv.Itoa(int(srv.Port))
+		uriList = append(uriList, r.Scheme+"://"+net.JoinHostPort(host, port))
+	}
+	return uriList, nil
+}
+
+func init() {
+	RegisterResolver("dns+srv", newDNSResolver)
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * rpc error for Go.                                      *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -23,6 +23,8 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"strings"
+	"sync"
 )
 
 // ErrTimeout represents a timeout error
@@ -30,7 +32,12 @@ var ErrTimeout = errors.New("timeout")
 var errServerIsAlreadyStarted = errors.New("The server is already started")
 var errServerIsNotStarted = errors.New("The server is not started")
 var errClientIsAlreadyClosed = errors.New("The Client is already closed")
+var errServiceIsShuttingDown = errors.New("The service is shutting down")
+var errReverseNotSupported = errors.New("The connection is not in full duplex mode")
+var errReverseNotEnabled = errors.New("The client doesn't handle the requests of the server")
+var errConnIsClosed = errors.New("The connection is closed")
 var errURIListEmpty = errors.New("uriList must contain at least one uri")
+var errResolverNotSupported = errors.New("The client doesn't support resolvers")
 var errNotSupportMultpleProtocol = errors.New("Not support multiple protocol.")
 
 // PanicError represents a panic error
@@ -50,6 +57,25 @@ func stack() []byte {
 	}
 }
 
+// callers returns the program counters of the caller of its caller, they
+// are cheaper than the stack, which is formatted only when it is needed.
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(3, pcs)]
+}
+
+func formatCallers(pcs []uintptr) string {
+	var buf strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&buf, "%s()\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			return buf.String()
+		}
+	}
+}
+
 // NewPanicError return a panic error
 func NewPanicError(v interface{}) *PanicError {
 	return &PanicError{v, stack()}
@@ -59,3 +85,144 @@ func NewPanicError(v interface{}) *PanicError {
 func (pe *PanicError) Error() string {
 	return fmt.Sprintf("%v", pe.Panic)
 }
+
+// RemoteError is the structured error which keeps its code, message and data
+// when it is transferred from the service to the client.
+//
+// The negative codes are reserved for the errors of hprose, such as
+// ErrTimeout.
+//
+// The Stack is the stack where the RemoteError is created, it is only
+// formatted and sent to the client when the service is in debug mode.
+//
+// It is only sent to the clients which request it by the RemoteError of
+// InvokeSettings, the other clients receive the message as before.
+type RemoteError struct {
+	Code    int
+	Message string
+	Data    interface{}
+	Stack   string
+	err     error
+	callers []uintptr
+}
+
+// NewRemoteError returns a RemoteError, data is optional.
+func NewRemoteError(code int, message string, data ...interface{}) *RemoteError {
+	e := &RemoteError{Code: code, Message: message, callers: callers()}
+	if len(data) > 0 {
+		e.Data = data[0]
+	}
+	return e
+}
+
+// Error implements the RemoteError Error method.
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the registered error of the code
+func (e *RemoteError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is a RemoteError with the same code
+func (e *RemoteError) Is(target error) bool {
+	t, ok := target.(*RemoteError)
+	return ok && t.Code == e.Code
+}
+
+var errorRegistry = struct {
+	sync.RWMutex
+	errors map[int]error
+}{errors: make(map[int]error)}
+
+// RegisterError registers the err with the code, so the err returned by the
+// service is sent with this code, and it is restored on the client,
+// errors.Is(clientErr, err) reports true.
+//
+// It panics if the code is negative, the negative codes are reserved.
+func RegisterError(code int, err error) {
+	if code < 0 {
+		panic(fmt.Errorf("The error code %d is reserved", code))
+	}
+	registerError(code, err)
+}
+
+func registerError(code int, err error) {
+	errorRegistry.Lock()
+	errorRegistry.errors[code] = err
+	errorRegistry.Unlock()
+}
+
+func registeredError(code int) error {
+	errorRegistry.RLock()
+	err := errorRegistry.errors[code]
+	errorRegistry.RUnlock()
+	return err
+}
+
+func toRemoteError(err error) *RemoteError {
+	var e *RemoteError
+	if errors.As(err, &e) {
+		return e
+	}
+	errorRegistry.RLock()
+	defer errorRegistry.RUnlock()
+	for code, registered := range errorRegistry.errors {
+		if errors.Is(err, registered) {
+			return &RemoteError{Code: code, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (e *RemoteError) toMap(debug bool) map[string]interface{} {
+	m := map[string]interface{}{
+		"code":    e.Code,
+		"message": e.Message,
+	}
+	if e.Data != nil {
+		m["data"] = e.Data
+	}
+	if debug {
+		stack := e.Stack
+		if stack == "" && e.callers != nil {
+			stack = formatCallers(e.callers)
+		}
+		if stack != "" {
+			m["stack"] = stack
+		}
+	}
+	return m
+}
+
+func toInt(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func newRemoteErrorFromMap(m map[string]interface{}) *RemoteError {
+	e := new(RemoteError)
+	e.Code = toInt(m["code"])
+	e.Message, _ = m["message"].(string)
+	e.Data = m["data"]
+	e.Stack, _ = m["stack"].(string)
+	e.err = registeredError(e.Code)
+	return e
+}
+
+// the codes of the errors of hprose
+const (
+	timeoutErrorCode = -1
+)
+
+func init() {
+	registerError(timeoutErrorCode, ErrTimeout)
+}
@@ -0,0 +1,75 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/error_test.go                                      *
+ *                                                        *
+ * hprose error test for Go.                              *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRemoteError_OptIn(t *testing.T) {
+	service := NewInProcService()
+	service.AddFunction("fail", func() error {
+		return NewRemoteError(42, "failed")
+	}, Options{})
+	response := service.Handle([]byte(`Cs4"fail"z`), NewServiceContext(service, nil))
+	if string(response) != `Es6"failed"z` {
+		t.Error(string(response))
+	}
+	response = service.Handle([]byte(`ECs4"fail"z`), NewServiceContext(service, nil))
+	context := &ClientContext{}
+	_, err := decode(response, nil, context)
+	var e *RemoteError
+	if !errors.As(err, &e) || e.Code != 42 || e.Message != "failed" {
+		t.Error(string(response), err)
+	}
+}
+
+func TestRegisterError_Reserved(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterError should panic with a reserved code")
+		}
+	}()
+	RegisterError(timeoutErrorCode, errors.New("timeout"))
+}
+
+func TestRemoteError_Timeout(t *testing.T) {
+	e := newRemoteErrorFromMap(map[string]interface{}{"code": 1, "message": "user"})
+	if errors.Is(e, ErrTimeout) {
+		t.Error("the code 1 is not ErrTimeout")
+	}
+	m := toRemoteError(ErrTimeout).toMap(false)
+	if !errors.Is(newRemoteErrorFromMap(m), ErrTimeout) {
+		t.Error(m)
+	}
+}
+
+func TestRemoteError_Stack(t *testing.T) {
+	e := NewRemoteError(42, "failed")
+	if _, ok := e.toMap(false)["stack"]; ok {
+		t.Error("the stack is sent without debug mode")
+	}
+	stack, _ := e.toMap(true)["stack"].(string)
+	if !strings.Contains(stack, "TestRemoteError_Stack") {
+		t.Error(stack)
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose http client for Go.                             *
  *                                                        *
- * LastModified: Oct 24, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"crypto/tls"
 	"net/url"
 	"strings"
@@ -149,14 +150,48 @@ func (client *FastHTTPClient) SetCompression(enable bool) {
 
 func (client *FastHTTPClient) sendAndReceive(
 	data []byte, context *ClientContext) ([]byte, error) {
+	ctx := context.Context()
+	done := ctx.Done()
+	if done == nil {
+		return client.send(ctx, data, context.URI, context.Timeout)
+	}
+	// fasthttp can't cancel a request in flight, so the request is sent in
+	// another goroutine, and it is abandoned when the context is done.
+	uri, timeout := context.URI, context.Timeout
+	response := make(chan socketResponse, 1)
+	go func() {
+		resp, err := client.send(ctx, data, uri, timeout)
+		response <- socketResponse{resp, err}
+	}()
+	select {
+	case resp := <-response:
+		return resp.data, resp.err
+	case <-done:
+		return nil, ctx.Err()
+	}
+}
+
+func (client *FastHTTPClient) send(
+	ctx gocontext.Context, data []byte,
+	uri string, timeout time.Duration) ([]byte, error) {
+	u := client.URL()
+	if uri != client.URI() {
+		var err error
+		if u, err = url.Parse(uri); err != nil {
+			return nil, err
+		}
+	}
 	client.cond.L.Lock()
-	client.limit()
+	err := client.limit(ctx)
 	client.cond.L.Unlock()
+	if err != nil {
+		return nil, err
+	}
 	req := fasthttp.AcquireRequest()
 	client.Header.CopyTo(&req.Header)
 	req.Header.SetMethod("POST")
-	client.loadCookie(req, client.url)
-	req.SetRequestURI(client.uri)
+	client.loadCookie(req, u)
+	req.SetRequestURI(uri)
 	req.SetBody(data)
 	req.Header.SetContentLength(len(data))
 	req.Header.SetContentType("application/hprose")
@@ -169,11 +204,11 @@ func (client *FastHTTPClient) sendAndReceive(
 		req.Header.Set("Content-Encoding", "gzip")
 	}
 	resp := fasthttp.AcquireResponse()
-	err := client.Client.DoTimeout(req, resp, context.Timeout)
+	err = client.Client.DoTimeout(req, resp, timeout)
 	if err != nil {
 		data = nil
 	} else {
-		data = resp.Body()
+		data = append([]byte(nil), resp.Body()...)
 		client.saveCookie(resp)
 	}
 	fasthttp.ReleaseRequest(req)
@@ -0,0 +1,371 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/fd_socket_trans.go                                 *
+ *                                                        *
+ * hprose full duplex socket transport for Go.            *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bufio"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type fullDuplexConnEntry struct {
+	conn    net.Conn
+	timer   *time.Timer
+	count   int
+	results map[uint32]chan socketResponse
+	// ready is closed when the #reverse request is responded
+	ready   chan struct{}
+	closed  bool
+	locker  sync.Mutex
+	wlocker sync.Mutex
+}
+
+func newFullDuplexConnEntry(conn net.Conn) *fullDuplexConnEntry {
+	return &fullDuplexConnEntry{
+		conn:    conn,
+		results: make(map[uint32]chan socketResponse),
+	}
+}
+
+func (entry *fullDuplexConnEntry) register(
+	id uint32, response chan socketResponse) bool {
+	entry.locker.Lock()
+	defer entry.locker.Unlock()
+	if entry.closed {
+		return false
+	}
+	entry.results[id] = response
+	return true
+}
+
+func (entry *fullDuplexConnEntry) unregister(
+	id uint32) (response chan socketResponse) {
+	entry.locker.Lock()
+	response = entry.results[id]
+	delete(entry.results, id)
+	entry.locker.Unlock()
+	return
+}
+
+func (entry *fullDuplexConnEntry) send(
+	id uint32, data []byte, timeout time.Duration) (err error) {
+	p := packet{fullDuplex: true, body: data}
+	fromUint32(p.id[:], id)
+	entry.wlocker.Lock()
+	if timeout > 0 {
+		err = entry.conn.SetWriteDeadline(time.Now().Add(timeout))
+	} else {
+		err = entry.conn.SetWriteDeadline(time.Time{})
+	}
+	if err == nil {
+		err = sendData(entry.conn, p)
+	}
+	entry.wlocker.Unlock()
+	return
+}
+
+func (entry *fullDuplexConnEntry) close(err error) bool {
+	entry.locker.Lock()
+	if entry.closed {
+		entry.locker.Unlock()
+		return false
+	}
+	entry.closed = true
+	results := entry.results
+	entry.results = nil
+	entry.locker.Unlock()
+	entry.conn.Close()
+	for _, response := range results {
+		response <- socketResponse{nil, err}
+	}
+	return true
+}
+
+type fullDuplexSocketTransport struct {
+	idleTimeout time.Duration
+	maxPoolSize int
+	connPool    []*fullDuplexConnEntry
+	connCount   int
+	nextid      uint32
+	createConn  func() net.Conn
+	// handleReverse handles the requests sent by the server
+	handleReverse func(request []byte) []byte
+	// reverseRequest returns the request sent over the new conns to enable
+	// the requests of the server, it returns nil if they are not handled
+	reverseRequest func() []byte
+	// onConnClose is called when a conn is closed
+	onConnClose func()
+	closed      bool
+	locker      sync.Mutex
+}
+
+func newFullDuplexSocketTransport() (fd *fullDuplexSocketTransport) {
+	fd = new(fullDuplexSocketTransport)
+	fd.idleTimeout = 0
+	fd.maxPoolSize = runtime.NumCPU()
+	fd.connCount = 0
+	fd.nextid = 0
+	fd.closed = false
+	return
+}
+
+func (fd *fullDuplexSocketTransport) setCreateConn(createConn func() net.Conn) {
+	fd.createConn = createConn
+}
+
+// IdleTimeout returns the conn pool idle timeout of hprose socket client
+func (fd *fullDuplexSocketTransport) IdleTimeout() time.Duration {
+	return fd.idleTimeout
+}
+
+// SetIdleTimeout sets the conn pool idle timeout of hprose socket client
+func (fd *fullDuplexSocketTransport) SetIdleTimeout(timeout time.Duration) {
+	fd.idleTimeout = timeout
+}
+
+// MaxPoolSize returns the max conn pool size of hprose socket client
+func (fd *fullDuplexSocketTransport) MaxPoolSize() int {
+	return fd.maxPoolSize
+}
+
+// SetMaxPoolSize sets the max conn pool size of hprose socket client
+func (fd *fullDuplexSocketTransport) SetMaxPoolSize(size int) {
+	if size > 0 {
+		fd.locker.Lock()
+		fd.maxPoolSize = size
+		fd.locker.Unlock()
+	}
+}
+
+// idleEntry returns the conn which has the fewest requests in flight,
+// it returns nil when a new conn should be created.
+func (fd *fullDuplexSocketTransport) idleEntry() *fullDuplexConnEntry {
+	var entry *fullDuplexConnEntry
+	for _, e := range fd.connPool {
+		if entry == nil || e.count < entry.count {
+			entry = e
+		}
+	}
+	if entry == nil || (entry.count > 0 && fd.connCount < fd.maxPoolSize) {
+		return nil
+	}
+	return entry
+}
+
+func (fd *fullDuplexSocketTransport) fetchConn() (entry *fullDuplexConnEntry) {
+	fd.locker.Lock()
+	if fd.closed {
+		fd.locker.Unlock()
+		panic(errClientIsAlreadyClosed)
+	}
+	if entry = fd.idleEntry(); entry != nil {
+		entry.count++
+		if entry.timer != nil {
+			entry.timer.Stop()
+			entry.timer = nil
+		}
+		fd.locker.Unlock()
+		return entry
+	}
+	fd.connCount++
+	fd.locker.Unlock()
+	defer func() {
+		if entry == nil {
+			fd.locker.Lock()
+			fd.connCount--
+			fd.locker.Unlock()
+		}
+	}()
+	conn := fd.createConn()
+	var request []byte
+	if fd.reverseRequest != nil {
+		request = fd.reverseRequest()
+	}
+	fd.locker.Lock()
+	if fd.closed {
+		fd.locker.Unlock()
+		conn.Close()
+		panic(errClientIsAlreadyClosed)
+	}
+	entry = newFullDuplexConnEntry(conn)
+	entry.count = 1
+	if request != nil {
+		entry.ready = make(chan struct{})
+	}
+	fd.connPool = append(fd.connPool, entry)
+	fd.locker.Unlock()
+	go fd.recvLoop(entry)
+	if request != nil {
+		go fd.negotiate(entry, request)
+	}
+	return entry
+}
+
+// negotiate sends the #reverse request over the new conn, the requests of
+// the client wait for its response, because the server handles the full
+// duplex requests concurrently.
+func (fd *fullDuplexSocketTransport) negotiate(
+	entry *fullDuplexConnEntry, request []byte) {
+	defer close(entry.ready)
+	id := atomic.AddUint32(&fd.nextid, 1) &^ reverseFlag
+	response := make(chan socketResponse, 1)
+	if !entry.register(id, response) {
+		return
+	}
+	if err := entry.send(id, request, 0); err != nil {
+		entry.unregister(id)
+		return
+	}
+	<-response
+}
+
+func (fd *fullDuplexSocketTransport) releaseConn(entry *fullDuplexConnEntry) {
+	fd.locker.Lock()
+	entry.count--
+	if entry.count == 0 && fd.idleTimeout > 0 && !fd.closed {
+		entry.timer = time.AfterFunc(fd.idleTimeout, func() {
+			fd.locker.Lock()
+			if entry.count > 0 {
+				fd.locker.Unlock()
+				return
+			}
+			fd.removeConn(entry)
+			fd.locker.Unlock()
+			fd.closeEntry(entry, errClientIsAlreadyClosed)
+		})
+	}
+	fd.locker.Unlock()
+}
+
+// removeConn must be called with fd.locker held.
+func (fd *fullDuplexSocketTransport) removeConn(entry *fullDuplexConnEntry) {
+	for i, e := range fd.connPool {
+		if e == entry {
+			fd.connPool = append(fd.connPool[:i], fd.connPool[i+1:]...)
+			fd.connCount--
+			break
+		}
+	}
+	if entry.timer != nil {
+		entry.timer.Stop()
+		entry.timer = nil
+	}
+}
+
+func (fd *fullDuplexSocketTransport) closeConn(
+	entry *fullDuplexConnEntry, err error) {
+	fd.locker.Lock()
+	fd.removeConn(entry)
+	fd.locker.Unlock()
+	fd.closeEntry(entry, err)
+}
+
+func (fd *fullDuplexSocketTransport) closeEntry(
+	entry *fullDuplexConnEntry, err error) {
+	if entry.close(err) && fd.onConnClose != nil {
+		go fd.onConnClose()
+	}
+}
+
+func (fd *fullDuplexSocketTransport) recvLoop(entry *fullDuplexConnEntry) {
+	reader := bufio.NewReader(entry.conn)
+	var data packet
+	for {
+		if err := recvData(reader, &data); err != nil {
+			fd.closeConn(entry, err)
+			return
+		}
+		if !data.fullDuplex {
+			continue
+		}
+		id := toUint32(data.id[:])
+		if isReverseID(id) {
+			if fd.handleReverse != nil {
+				go func(request []byte) {
+					entry.send(id, fd.handleReverse(request), 0)
+				}(data.body)
+			}
+			continue
+		}
+		response := entry.unregister(id)
+		if response != nil {
+			response <- socketResponse{data.body, nil}
+		}
+	}
+}
+
+func (fd *fullDuplexSocketTransport) close() {
+	fd.locker.Lock()
+	if fd.closed {
+		fd.locker.Unlock()
+		return
+	}
+	fd.closed = true
+	connPool := fd.connPool
+	fd.connPool = nil
+	fd.connCount = 0
+	fd.locker.Unlock()
+	for _, entry := range connPool {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		fd.closeEntry(entry, errClientIsAlreadyClosed)
+	}
+}
+
+func (fd *fullDuplexSocketTransport) sendAndReceive(
+	data []byte, context *ClientContext) ([]byte, error) {
+	entry := fd.fetchConn()
+	defer fd.releaseConn(entry)
+	ctx := context.Context()
+	timer := time.NewTimer(context.Timeout)
+	defer timer.Stop()
+	if entry.ready != nil {
+		select {
+		case <-entry.ready:
+		case <-timer.C:
+			return nil, ErrTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	id := atomic.AddUint32(&fd.nextid, 1) &^ reverseFlag
+	response := make(chan socketResponse, 1)
+	if !entry.register(id, response) {
+		return nil, errClientIsAlreadyClosed
+	}
+	if err := entry.send(id, data, context.Timeout); err != nil {
+		fd.closeConn(entry, err)
+		return nil, err
+	}
+	select {
+	case resp := <-response:
+		return resp.data, resp.err
+	case <-timer.C:
+		entry.unregister(id)
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		entry.unregister(id)
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/file_resolver.go                                   *
+ *                                                        *
+ * hprose file resolver and registrar for Go.             *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileResolver resolves the service addresses from a json file which
+// contains an array of uri strings, such as:
+//
+//	["tcp://10.0.0.1:4321", "tcp://10.0.0.2:4321"]
+//
+// The target address is "static+file:///path/to/file.json", the file is
+// reloaded every 5 seconds by default, it can be changed by the interval
+// query parameter, such as "static+file:///etc/svc.json?interval=1s".
+type FileResolver struct {
+	*pollingResolver
+	Path string
+}
+
+// NewFileResolver is the constructor of FileResolver
+func NewFileResolver(path string, interval time.Duration) *FileResolver {
+	r := &FileResolver{Path: path}
+	r.pollingResolver = newPollingResolver(r.resolve, interval)
+	return r
+}
+
+func newFileResolver(target *url.URL) (Resolver, error) {
+	interval, err := getInterval(target, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	path := target.Path
+	if path == "" {
+		path = target.Opaque
+	}
+	return NewFileResolver(path, interval), nil
+}
+
+func (r *FileResolver) resolve() ([]string, error) {
+	return readURIListFile(r.Path)
+}
+
+func readURIListFile(path string) (uriList []string, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &uriList); err != nil {
+		return nil, err
+	}
+	return uriList, nil
+}
+
+func writeURIListFile(path string, uriList []string) error {
+	data, err := json.MarshalIndent(uriList, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// FileRegistrar adds the server address into the json file read by
+// FileResolver when the server starts, and removes it when the server
+// is closed.
+//
+// The file is replaced atomically, but concurrent updates from different
+// processes are not serialized.
+type FileRegistrar struct {
+	Path   string
+	locker sync.Mutex
+}
+
+// NewFileRegistrar is the constructor of FileRegistrar
+func NewFileRegistrar(path string) *FileRegistrar {
+	return &FileRegistrar{Path: path}
+}
+
+func (r *FileRegistrar) update(change func(uriList []string) []string) error {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	uriList, err := readURIListFile(r.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeURIListFile(r.Path, change(uriList))
+}
+
+// Register adds uri into the file
+func (r *FileRegistrar) Register(uri string) error {
+	return r.update(func(uriList []string) []string {
+		for _, u := range uriList {
+			if u == uri {
+				return uriList
+			}
+		}
+		return append(uriList, uri)
+	})
+}
+
+// Deregister removes uri from the file
+func (r *FileRegistrar) Deregister(uri string) error {
+	return r.update(func(uriList []string) []string {
+		result := make([]string, 0, len(uriList))
+		for _, u := range uriList {
+			if u != uri {
+				result = append(result, u)
+			}
+		}
+		return result
+	})
+}
+
+func init() {
+	RegisterResolver("static+file", newFileResolver)
+}
@@ -12,14 +12,17 @@
  *                                                        *
  * hprose filter interface for Go.                        *
  *                                                        *
- * LastModified: Oct 25, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
 
 package rpc
 
-import "sync"
+import (
+	"errors"
+	"sync"
+)
 
 // Filter is hprose filter
 type Filter interface {
@@ -27,6 +30,98 @@ type Filter interface {
 	OutputFilter(data []byte, context Context) []byte
 }
 
+// ErrorFilter is the hprose filter which reports its failures, such as the
+// corrupted compressed data, instead of passing the garbage to the decoder.
+// It is added to the clients and the services by NewErrorFilter.
+type ErrorFilter interface {
+	InputFilter(data []byte, context Context) ([]byte, error)
+	OutputFilter(data []byte, context Context) ([]byte, error)
+}
+
+// FilterError is returned when an ErrorFilter fails
+type FilterError struct {
+	// Input is true if the InputFilter fails, otherwise the OutputFilter
+	// fails
+	Input bool
+	Err   error
+}
+
+// Error implements the FilterError Error method.
+func (e *FilterError) Error() string {
+	if e.Input {
+		return "input filter failed: " + e.Err.Error()
+	}
+	return "output filter failed: " + e.Err.Error()
+}
+
+// Unwrap returns the error of the filter
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// isFilterError reports whether err is a FilterError, it is the local
+// error, so it isn't the failure of the service address, and it isn't retried
+func isFilterError(err error) bool {
+	var e *FilterError
+	return errors.As(err, &e)
+}
+
+type errorFilter struct {
+	filter ErrorFilter
+}
+
+// NewErrorFilter returns the Filter of the ErrorFilter, the failures of it
+// are returned by the client invocations as FilterError, and the services
+// send them to the clients as the error responses.
+//
+// The methods of the returned Filter panic with the FilterError if they are
+// called directly.
+func NewErrorFilter(filter ErrorFilter) Filter {
+	return errorFilter{filter}
+}
+
+// InputFilter for Filter
+func (f errorFilter) InputFilter(data []byte, context Context) []byte {
+	data, err := filterInput(f, data, context)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// OutputFilter for Filter
+func (f errorFilter) OutputFilter(data []byte, context Context) []byte {
+	data, err := filterOutput(f, data, context)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func filterInput(filter Filter, data []byte, context Context) ([]byte, error) {
+	f, ok := filter.(errorFilter)
+	if !ok {
+		return filter.InputFilter(data, context), nil
+	}
+	data, err := f.filter.InputFilter(data, context)
+	if err != nil {
+		return nil, &FilterError{Input: true, Err: err}
+	}
+	return data, nil
+}
+
+func filterOutput(filter Filter, data []byte, context Context) ([]byte, error) {
+	f, ok := filter.(errorFilter)
+	if !ok {
+		return filter.OutputFilter(data, context), nil
+	}
+	data, err := f.filter.OutputFilter(data, context)
+	if err != nil {
+		return nil, &FilterError{Input: false, Err: err}
+	}
+	return data, nil
+}
+
 // filterManager is the filter manager
 type filterManager struct {
 	filters  []Filter
@@ -64,8 +159,7 @@ func (fm *filterManager) FilterByIndex(index int) Filter {
 // SetFilter will replace the current filter settings
 func (fm *filterManager) SetFilter(filter ...Filter) {
 	fm.fmLocker.Lock()
-	fm.filters = make([]Filter, len(filter))
-	fm.AddFilter(filter...)
+	fm.filters = append([]Filter(nil), filter...)
 	fm.fmLocker.Unlock()
 }
 
@@ -110,20 +204,26 @@ func (fm *filterManager) RemoveFilter(filter ...Filter) {
 	}
 }
 
-func (fm *filterManager) inputFilter(data []byte, context Context) []byte {
+func (fm *filterManager) inputFilter(
+	data []byte, context Context) (_ []byte, err error) {
 	fm.fmLocker.RLock()
 	defer fm.fmLocker.RUnlock()
 	for i := len(fm.filters) - 1; i >= 0; i-- {
-		data = fm.filters[i].InputFilter(data, context)
+		if data, err = filterInput(fm.filters[i], data, context); err != nil {
+			return nil, err
+		}
 	}
-	return data
+	return data, nil
 }
 
-func (fm *filterManager) outputFilter(data []byte, context Context) []byte {
+func (fm *filterManager) outputFilter(
+	data []byte, context Context) (_ []byte, err error) {
 	fm.fmLocker.RLock()
 	defer fm.fmLocker.RUnlock()
 	for i := range fm.filters {
-		data = fm.filters[i].OutputFilter(data, context)
+		if data, err = filterOutput(fm.filters[i], data, context); err != nil {
+			return nil, err
+		}
 	}
-	return data
+	return data, nil
 }
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose client filter for Go.                           *
  *                                                        *
- * LastModified: Oct 27, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -53,7 +53,15 @@ func (filter *ClientFilter) InputFilter(data []byte, context rpc.Context) []byte
 		if err != nil {
 			e := err.(map[string]interface{})
 			writer.WriteByte(io.TagError)
-			writer.WriteString(e["message"].(string))
+			if code, ok := e["code"].(float64); ok && code != -1 {
+				writer.Serialize(map[string]interface{}{
+					"code":    int(code),
+					"message": e["message"],
+					"data":    e["data"],
+				})
+			} else {
+				writer.WriteString(e["message"].(string))
+			}
 		} else {
 			writer.WriteByte(io.TagResult)
 			writer.Serialize(response["result"])
@@ -76,6 +84,9 @@ func (filter *ClientFilter) OutputFilter(data []byte, context rpc.Context) []byt
 		reader := io.NewReader(data, false)
 		reader.JSONCompatible = true
 		tag, _ := reader.ReadByte()
+		if tag == io.TagError {
+			tag, _ = reader.ReadByte()
+		}
 		if tag == io.TagCall {
 			request["method"] = reader.ReadString()
 			tag, _ = reader.ReadByte()
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose service filter for Go.                          *
  *                                                        *
- * LastModified: Oct 29, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -63,6 +63,8 @@ func (filter ServiceFilter) InputFilter(data []byte, context rpc.Context) []byte
 			}
 		}
 		writer := io.NewWriter(true)
+		// the codes of the errors are kept in the responses
+		writer.WriteByte(io.TagError)
 		n := len(requests)
 		responses := make([]map[string]interface{}, n)
 		for i, request := range requests {
@@ -99,9 +101,20 @@ func (filter ServiceFilter) OutputFilter(data []byte, context rpc.Context) []byt
 			} else if tag == io.TagError {
 				reader.Reset()
 				err := make(map[string]interface{})
-				err["code"] = -1
-				message := reader.ReadString()
-				err["message"] = message
+				tag, _ = reader.ReadByte()
+				reader.UnreadByte()
+				if tag == io.TagMap {
+					var e map[string]interface{}
+					reader.Unserialize(&e)
+					err["code"] = e["code"]
+					err["message"] = e["message"]
+					if data, ok := e["data"]; ok {
+						err["data"] = data
+					}
+				} else {
+					err["code"] = -1
+					err["message"] = reader.ReadString()
+				}
 				tag, _ = reader.ReadByte()
 				response["error"] = err
 			}
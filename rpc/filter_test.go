@@ -0,0 +1,97 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/filter_test.go                                     *
+ *                                                        *
+ * hprose filter test for Go.                             *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type testFailFilter struct {
+	count int
+}
+
+func (f *testFailFilter) InputFilter(data []byte, context Context) ([]byte, error) {
+	return data, nil
+}
+
+func (f *testFailFilter) OutputFilter(data []byte, context Context) ([]byte, error) {
+	f.count++
+	return nil, errors.New("bad output")
+}
+
+type testBalancer struct {
+	uri  string
+	done []error
+}
+
+func (b *testBalancer) SetURIList(uriList []string) {
+	b.uri = uriList[0]
+}
+
+func (b *testBalancer) Select(context *ClientContext) string {
+	return b.uri
+}
+
+func (b *testBalancer) Done(uri string, err error) {
+	b.done = append(b.done, err)
+}
+
+type testChanErrorEvent chan error
+
+func (e testChanErrorEvent) OnError(name string, err error) {
+	e <- err
+}
+
+func TestFilterError_NotRetried(t *testing.T) {
+	server := NewInProcServer("inproc://filter")
+	server.AddFunction("hello", func() string { return "hi" }, Options{})
+	server.Handle()
+	defer server.Close()
+	client := NewInProcClient(server.URI())
+	defer client.Close()
+	event := make(testChanErrorEvent, 2)
+	client.SetEvent(event)
+	balancer := new(testBalancer)
+	client.SetBalancer(balancer)
+	filter := new(testFailFilter)
+	client.AddFilter(NewErrorFilter(filter))
+	var stub struct {
+		Hello func(func(string)) `idempotent:"true" retry:"3"`
+	}
+	client.UseService(&stub)
+	stub.Hello(func(string) {})
+	var e *FilterError
+	if err := <-event; !errors.As(err, &e) {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-event:
+		t.Error("reported twice", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if filter.count != 1 {
+		t.Error("retried", filter.count)
+	}
+	if len(balancer.done) != 1 || balancer.done[0] != nil {
+		t.Error(balancer.done)
+	}
+}
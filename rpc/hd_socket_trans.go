@@ -12,7 +12,7 @@
  *                                                        *
  * hprose half duplex socket transport for Go.            *
  *                                                        *
- * LastModified: Oct 21, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"net"
 	"runtime"
 	"sync"
@@ -72,7 +73,9 @@ func (hd *halfDuplexSocketTransport) MaxPoolSize() int {
 
 // SetMaxPoolSize sets the max conn pool size of hprose socket client
 func (hd *halfDuplexSocketTransport) SetMaxPoolSize(size int) {
-	if size > 0 {
+	hd.cond.L.Lock()
+	defer hd.cond.L.Unlock()
+	if size > 0 && hd.connPool != nil {
 		pool := make(chan *halfDuplexConnEntry, size)
 		for i := 0; i < len(hd.connPool); i++ {
 			select {
@@ -105,28 +108,58 @@ func (hd *halfDuplexSocketTransport) getConn() *halfDuplexConnEntry {
 	panic(errClientIsAlreadyClosed)
 }
 
-func (hd *halfDuplexSocketTransport) fetchConn() *halfDuplexConnEntry {
+// fetchConn waits for an idle conn or creates a new one if the pool isn't
+// full, it returns ctx.Err() if ctx is done while waiting.
+func (hd *halfDuplexSocketTransport) fetchConn(
+	ctx gocontext.Context) (*halfDuplexConnEntry, error) {
+	var stop func()
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
 	hd.cond.L.Lock()
 	for {
 		entry := hd.getConn()
 		if entry != nil && entry.conn != nil {
 			hd.cond.L.Unlock()
-			return entry
+			return entry, nil
 		}
 		if int(atomic.AddInt32(&hd.connCount, 1)) <= cap(hd.connPool) {
 			hd.cond.L.Unlock()
-			return &halfDuplexConnEntry{conn: hd.createConn()}
+			return &halfDuplexConnEntry{conn: hd.newConn()}, nil
 		}
 		atomic.AddInt32(&hd.connCount, -1)
+		if err := ctx.Err(); err != nil {
+			hd.cond.L.Unlock()
+			return nil, err
+		}
+		if stop == nil {
+			stop = wakeOnDone(ctx, &hd.cond)
+		}
 		hd.cond.Wait()
 	}
 }
 
+func (hd *halfDuplexSocketTransport) newConn() net.Conn {
+	defer func() {
+		if e := recover(); e != nil {
+			hd.releaseCount()
+			hd.cond.Signal()
+			panic(e)
+		}
+	}()
+	return hd.createConn()
+}
+
 func (hd *halfDuplexSocketTransport) close() {
-	if hd.connPool != nil {
-		connPool := hd.connPool
-		hd.connPool = nil
-		hd.connCount = 0
+	hd.cond.L.Lock()
+	connPool := hd.connPool
+	hd.connPool = nil
+	atomic.StoreInt32(&hd.connCount, 0)
+	hd.cond.L.Unlock()
+	hd.cond.Broadcast()
+	if connPool != nil {
 		close(connPool)
 		for entry := range connPool {
 			if entry.timer != nil {
@@ -139,28 +172,92 @@ func (hd *halfDuplexSocketTransport) close() {
 	}
 }
 
+// releaseConn puts the entry back to the pool, the conn is closed if the
+// transport is closed or the pool is full
+func (hd *halfDuplexSocketTransport) releaseConn(entry *halfDuplexConnEntry) {
+	hd.cond.L.Lock()
+	released := false
+	if hd.connPool != nil {
+		select {
+		case hd.connPool <- entry:
+			released = true
+		default:
+		}
+	}
+	hd.cond.L.Unlock()
+	if !released {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		hd.closeConn(entry.conn)
+	}
+	hd.cond.Signal()
+}
+
 func (hd *halfDuplexSocketTransport) closeConn(conn net.Conn) {
 	conn.Close()
-	atomic.AddInt32(&hd.connCount, -1)
+	hd.releaseCount()
+}
+
+// releaseCount decreases the conn count, it is reset to 0 when the transport
+// is closed, so the conns checked out before are not counted again.
+func (hd *halfDuplexSocketTransport) releaseCount() {
+	hd.cond.L.Lock()
+	if hd.connPool != nil {
+		atomic.AddInt32(&hd.connCount, -1)
+	}
+	hd.cond.L.Unlock()
+}
+
+// watchConn interrupts the io on conn when ctx is done.
+// The returned stop func must be called before conn is reused.
+func watchConn(ctx gocontext.Context, conn net.Conn) (stop func()) {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	quit := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			conn.SetDeadline(time.Now())
+		case <-quit:
+		}
+		close(exited)
+	}()
+	return func() {
+		close(quit)
+		<-exited
+	}
 }
 
 func (hd *halfDuplexSocketTransport) sendAndReceive(
 	data []byte, context *ClientContext) ([]byte, error) {
-	entry := hd.fetchConn()
+	ctx := context.Context()
+	entry, err := hd.fetchConn(ctx)
+	if err != nil {
+		return nil, err
+	}
 	conn := entry.conn
-	err := conn.SetDeadline(time.Now().Add(context.Timeout))
+	stop := watchConn(ctx, conn)
+	err = conn.SetDeadline(time.Now().Add(context.Timeout))
 	if err == nil {
 		err = hdSendData(conn, data)
 	}
 	if err == nil {
 		data, err = hdRecvData(conn, data)
 	}
+	stop()
 	if err == nil {
 		err = conn.SetDeadline(time.Time{})
 	}
 	if err != nil {
 		hd.closeConn(conn)
 		hd.cond.Signal()
+		if e := ctx.Err(); e != nil {
+			err = e
+		}
 		return nil, err
 	}
 	if hd.idleTimeout > 0 {
@@ -174,7 +271,6 @@ func (hd *halfDuplexSocketTransport) sendAndReceive(
 			entry.timer.Reset(hd.idleTimeout)
 		}
 	}
-	hd.connPool <- entry
-	hd.cond.Signal()
+	hd.releaseConn(entry)
 	return data, nil
 }
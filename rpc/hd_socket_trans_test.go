@@ -0,0 +1,69 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/hd_socket_trans_test.go                            *
+ *                                                        *
+ * hprose half duplex socket transport test for Go.       *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestHalfDuplexSocketTransport(size int) *halfDuplexSocketTransport {
+	hd := newHalfDuplexSocketTransport()
+	hd.SetMaxPoolSize(size)
+	hd.setCreateConn(func() net.Conn {
+		conn, _ := net.Pipe()
+		return conn
+	})
+	return hd
+}
+
+func TestHalfDuplexSocketTransport_ReleaseAfterClose(t *testing.T) {
+	hd := newTestHalfDuplexSocketTransport(2)
+	entry1, _ := hd.fetchConn(gocontext.Background())
+	entry2, _ := hd.fetchConn(gocontext.Background())
+	hd.close()
+	hd.releaseConn(entry1)
+	hd.closeConn(entry2.conn)
+	if hd.connCount != 0 {
+		t.Error(hd.connCount)
+	}
+}
+
+func TestHalfDuplexSocketTransport_FetchConnContext(t *testing.T) {
+	hd := newTestHalfDuplexSocketTransport(1)
+	defer hd.close()
+	entry, err := hd.fetchConn(gocontext.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := gocontext.WithTimeout(
+		gocontext.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = hd.fetchConn(ctx); err != gocontext.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	// the conn released after the cancellation is still available
+	hd.releaseConn(entry)
+	if e, err := hd.fetchConn(gocontext.Background()); err != nil || e != entry {
+		t.Error(e, err)
+	}
+}
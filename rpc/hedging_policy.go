@@ -0,0 +1,183 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/hedging_policy.go                                  *
+ *                                                        *
+ * hprose hedging policy for Go.                          *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	hedgingSamples    = 100
+	hedgingMinSamples = 20
+)
+
+// HedgingPolicy is the hedging policy of hprose client.
+//
+// The idempotent request is sent to another service address in the uri list
+// if no response is received after the delay, the first successful response
+// is used, and the other requests are cancelled.
+type HedgingPolicy struct {
+	// Delay is the delay before a hedged request is sent. If it is 0, the
+	// observed p95 latency is used, and no hedged request is sent until
+	// enough latencies are observed.
+	Delay time.Duration
+	// MaxAttempts is the max number of requests including the first one,
+	// the default value is 2.
+	MaxAttempts int
+	latencies   [hedgingSamples]time.Duration
+	count       int
+	next        int
+	locker      sync.Mutex
+}
+
+func (policy *HedgingPolicy) maxAttempts(n int) int {
+	max := policy.MaxAttempts
+	if max <= 0 {
+		max = 2
+	}
+	if max > n {
+		max = n
+	}
+	return max
+}
+
+func (policy *HedgingPolicy) observe(latency time.Duration) {
+	policy.locker.Lock()
+	policy.latencies[policy.next] = latency
+	policy.next = (policy.next + 1) % hedgingSamples
+	if policy.count < hedgingSamples {
+		policy.count++
+	}
+	policy.locker.Unlock()
+}
+
+func (policy *HedgingPolicy) delay() time.Duration {
+	if policy.Delay > 0 {
+		return policy.Delay
+	}
+	policy.locker.Lock()
+	if policy.count < hedgingMinSamples {
+		policy.locker.Unlock()
+		return 0
+	}
+	latencies := make([]time.Duration, policy.count)
+	copy(latencies, policy.latencies[:policy.count])
+	policy.locker.Unlock()
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	return latencies[len(latencies)*95/100]
+}
+
+type hedgeResult struct {
+	response []byte
+	err      error
+	context  *ClientContext
+	latency  time.Duration
+}
+
+// nextURI returns the service address after the last used one which is
+// not used yet
+func (client *baseClient) nextURI(used []string) string {
+	uriList := client.URIList()
+	n := len(uriList)
+	start := 0
+	last := used[len(used)-1]
+	for i, uri := range uriList {
+		if uri == last {
+			start = i
+			break
+		}
+	}
+	for i := 1; i < n; i++ {
+		uri := uriList[(start+i)%n]
+		found := false
+		for _, u := range used {
+			if u == uri {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return uri
+		}
+	}
+	return ""
+}
+
+func (client *baseClient) hedgeRequest(
+	policy *HedgingPolicy,
+	request []byte,
+	context *ClientContext) ([]byte, error) {
+	ctx, cancel := gocontext.WithCancel(context.Context())
+	defer cancel()
+	max := policy.maxAttempts(len(client.URIList()))
+	results := make(chan hedgeResult, max)
+	send := func(uri string, balancer Balancer) {
+		c := context.clone(ctx)
+		go func() {
+			start := time.Now()
+			response, err := client.sendTo(request, c, uri, balancer)
+			results <- hedgeResult{response, err, c, time.Since(start)}
+		}()
+	}
+	uri, balancer := client.selectURI(context)
+	send(uri, balancer)
+	used := []string{uri}
+	pending := 1
+	var timer <-chan time.Time
+	delay := policy.delay()
+	if delay > 0 && max > 1 {
+		timer = time.After(delay)
+	}
+	var err error
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				policy.observe(result.latency)
+				context.URI = result.context.URI
+				for k, v := range result.context.UserData() {
+					context.SetInterface(k, v)
+				}
+				return result.response, nil
+			}
+			if isFilterError(result.err) {
+				return nil, result.err
+			}
+			err = result.err
+		case <-timer:
+			timer = nil
+			if uri = client.nextURI(used); uri != "" {
+				send(uri, nil)
+				used = append(used, uri)
+				pending++
+				if len(used) < max {
+					timer = time.After(delay)
+				}
+			}
+		}
+	}
+	return nil, err
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose http client for Go.                             *
  *                                                        *
- * LastModified: Oct 24, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -119,12 +119,21 @@ func (client *HTTPClient) readAll(
 func (client *HTTPClient) sendAndReceive(
 	data []byte, context *ClientContext) ([]byte, error) {
 	client.cond.L.Lock()
-	client.limit()
+	err := client.limit(context.Context())
 	client.cond.L.Unlock()
-	req, err := http.NewRequest("POST", client.uri, hio.NewByteReader(data))
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		client.cond.L.Lock()
+		client.unlimit()
+		client.cond.L.Unlock()
+	}()
+	req, err := http.NewRequest("POST", context.URI, hio.NewByteReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(context.Context())
 	for key, values := range client.Header {
 		for _, value := range values {
 			req.Header.Add(key, value)
@@ -141,8 +150,5 @@ func (client *HTTPClient) sendAndReceive(
 	if err == nil {
 		err = resp.Body.Close()
 	}
-	client.cond.L.Lock()
-	client.unlimit()
-	client.cond.L.Unlock()
 	return data, err
 }
@@ -0,0 +1,225 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/http_server.go                                     *
+ *                                                        *
+ * hprose http server for Go.                             *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// baseHTTPServer is the base struct of HTTPServer and WebSocketServer
+type baseHTTPServer struct {
+	starter
+	// Registrar announces the server address when Handle starts,
+	// and withdraws it when Close
+	Registrar Registrar
+	// TLSConfig is used by the https and wss servers, the certificate
+	// is loaded from CertFile and KeyFile if they are not empty.
+	TLSConfig *tls.Config
+	CertFile  string
+	KeyFile   string
+	uri       string
+	listener  net.Listener
+	server    *http.Server
+}
+
+func (server *baseHTTPServer) initBaseHTTPServer(s Server, uri string) {
+	server.starter.server = s
+	server.uri = uri
+}
+
+// URI return the real address of this server
+func (server *baseHTTPServer) URI() string {
+	if server.listener == nil {
+		panic(errServerIsNotStarted)
+	}
+	u, err := url.Parse(server.uri)
+	if err != nil {
+		panic(err)
+	}
+	return u.Scheme + "://" + server.listener.Addr().String() + u.Path
+}
+
+func (server *baseHTTPServer) getTLSConfig() (config *tls.Config, err error) {
+	if server.TLSConfig != nil {
+		config = server.TLSConfig.Clone()
+	} else {
+		config = new(tls.Config)
+	}
+	if server.CertFile != "" || server.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(server.CertFile, server.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+	if len(config.Certificates) == 0 && config.GetCertificate == nil {
+		return nil, errors.New("The TLSConfig or CertFile and KeyFile is required")
+	}
+	return config, nil
+}
+
+func (server *baseHTTPServer) listenAndServe(
+	service http.Handler, tlsSchemes ...string) (err error) {
+	if server.listener != nil {
+		return errServerIsAlreadyStarted
+	}
+	u, err := url.Parse(server.uri)
+	if err != nil {
+		return err
+	}
+	var config *tls.Config
+	for _, scheme := range tlsSchemes {
+		if u.Scheme == scheme {
+			if config, err = server.getTLSConfig(); err != nil {
+				return err
+			}
+		}
+	}
+	listener, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+	handler := service
+	if u.Path != "" && u.Path != "/" {
+		mux := http.NewServeMux()
+		mux.Handle(u.Path, service)
+		handler = mux
+	}
+	server.listener = listener
+	if server.Registrar != nil {
+		if err = server.Registrar.Register(server.URI()); err != nil {
+			server.listener = nil
+			listener.Close()
+			return err
+		}
+	}
+	server.server = &http.Server{Handler: handler, TLSConfig: config}
+	go server.server.Serve(listener)
+	return nil
+}
+
+// close stops accepting new connections, the keep-alive connections are
+// closed after their current requests.
+func (server *baseHTTPServer) close(event ServiceEvent) {
+	if server.listener != nil {
+		if server.Registrar != nil {
+			if err := server.Registrar.Deregister(server.URI()); err != nil {
+				fireErrorEvent(event, err, nil)
+			}
+		}
+		listener := server.listener
+		server.listener = nil
+		server.server.SetKeepAlivesEnabled(false)
+		listener.Close()
+	}
+}
+
+func (server *baseHTTPServer) shutdown(
+	ctx gocontext.Context, service Service, event ServiceEvent) error {
+	httpServer := server.server
+	server.close(event)
+	if httpServer == nil {
+		return service.Shutdown(ctx)
+	}
+	return ShutdownHTTPServer(ctx, httpServer, service)
+}
+
+// HTTPServer is a hprose http server
+type HTTPServer struct {
+	HTTPService
+	baseHTTPServer
+}
+
+// NewHTTPServer is the constructor for HTTPServer
+func NewHTTPServer(uri string) (server *HTTPServer) {
+	if uri == "" {
+		uri = "http://127.0.0.1:0/"
+	}
+	server = new(HTTPServer)
+	server.initHTTPService()
+	server.initBaseHTTPServer(server, uri)
+	return
+}
+
+// Handle the hprose http server
+func (server *HTTPServer) Handle() (err error) {
+	if server.listener == nil {
+		server.reopen()
+	}
+	return server.listenAndServe(&server.HTTPService, "https")
+}
+
+// Close the hprose http server
+func (server *HTTPServer) Close() {
+	server.close(server.Event)
+}
+
+// Shutdown the hprose http server gracefully, it stops accepting new
+// connections, releases the push subscribers, and waits for the in-flight
+// requests to finish until ctx is done.
+func (server *HTTPServer) Shutdown(ctx gocontext.Context) error {
+	return server.shutdown(ctx, &server.HTTPService, server.Event)
+}
+
+// WebSocketServer is a hprose websocket server
+type WebSocketServer struct {
+	WebSocketService
+	baseHTTPServer
+}
+
+// NewWebSocketServer is the constructor for WebSocketServer
+func NewWebSocketServer(uri string) (server *WebSocketServer) {
+	if uri == "" {
+		uri = "ws://127.0.0.1:0/"
+	}
+	server = new(WebSocketServer)
+	server.initWebSocketService()
+	server.initBaseHTTPServer(server, uri)
+	return
+}
+
+// Handle the hprose websocket server
+func (server *WebSocketServer) Handle() (err error) {
+	if server.listener == nil {
+		server.reopen()
+	}
+	return server.listenAndServe(&server.WebSocketService, "wss")
+}
+
+// Close the hprose websocket server
+func (server *WebSocketServer) Close() {
+	server.close(server.Event)
+}
+
+// Shutdown the hprose websocket server gracefully, it stops accepting new
+// connections, releases the push subscribers, waits for the in-flight
+// requests to finish until ctx is done, and then closes the websocket
+// connections.
+func (server *WebSocketServer) Shutdown(ctx gocontext.Context) error {
+	return server.shutdown(ctx, &server.WebSocketService, server.Event)
+}
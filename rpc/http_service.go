@@ -12,7 +12,7 @@
  *                                                        *
  * hprose http service for Go.                            *
  *                                                        *
- * LastModified: Oct 9, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -44,6 +44,7 @@ func (context *HTTPContext) initHTTPContext(
 	context.initServiceContext(service)
 	context.Response = response
 	context.Request = request
+	context.setContext(request.Context())
 }
 
 // HTTPService is the hprose http service
@@ -79,12 +80,16 @@ func httpFixArguments(args []reflect.Value, context ServiceContext) {
 // NewHTTPService is the constructor of HTTPService
 func NewHTTPService() (service *HTTPService) {
 	service = new(HTTPService)
+	service.initHTTPService()
+	return
+}
+
+func (service *HTTPService) initHTTPService() {
 	service.initBaseHTTPService()
 	service.contextPool = sync.Pool{
 		New: func() interface{} { return new(HTTPContext) },
 	}
 	service.FixArguments = httpFixArguments
-	return
 }
 
 func (service *HTTPService) acquireContext() (context *HTTPContext) {
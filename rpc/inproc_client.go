@@ -0,0 +1,87 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/inproc_client.go                                   *
+ *                                                        *
+ * hprose in-process client for Go.                       *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type inprocTransport struct {
+	closed int32
+}
+
+func (trans *inprocTransport) SendAndReceive(
+	data []byte, context *ClientContext) ([]byte, error) {
+	if atomic.LoadInt32(&trans.closed) != 0 {
+		return nil, errClientIsAlreadyClosed
+	}
+	service, err := getInProcService(context.URI)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Context()
+	response := make(chan []byte, 1)
+	go func() {
+		response <- service.Handle(data, NewServiceContext(service, ctx))
+	}()
+	timer := time.NewTimer(context.Timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-response:
+		return resp, nil
+	case <-timer.C:
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (trans *inprocTransport) Close() {
+	atomic.StoreInt32(&trans.closed, 1)
+}
+
+// InProcClient is the hprose in-process client, the requests are passed
+// to the service registered by RegisterInProcService directly, but they
+// still go through all of the filters and handlers of the client and the
+// service.
+type InProcClient struct {
+	TransportClient
+}
+
+// NewInProcClient is the constructor of InProcClient
+func NewInProcClient(uri ...string) (client *InProcClient) {
+	client = new(InProcClient)
+	client.initBaseClient()
+	client.Transport = new(inprocTransport)
+	client.SendAndReceive = client.Transport.SendAndReceive
+	client.SetURIList(uri)
+	return
+}
+
+func newInProcClient(uri ...string) Client {
+	return NewInProcClient(uri...)
+}
+
+// SetURIList set a list of server addresses
+func (client *InProcClient) SetURIList(uriList []string) {
+	checkAddresses(uriList, inprocSchemes)
+	client.baseClient.SetURIList(uriList)
+}
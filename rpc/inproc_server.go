@@ -0,0 +1,86 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/inproc_server.go                                   *
+ *                                                        *
+ * hprose in-process server for Go.                       *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"net/url"
+)
+
+// InProcServer is a hprose in-process server
+type InProcServer struct {
+	InProcService
+	starter
+	uri     string
+	name    string
+	started bool
+}
+
+// NewInProcServer is the constructor for InProcServer
+func NewInProcServer(uri string) (server *InProcServer) {
+	if uri == "" {
+		uri = "inproc://hprose"
+	}
+	server = new(InProcServer)
+	server.initInProcService()
+	server.starter.server = server
+	server.uri = uri
+	return
+}
+
+// URI return the real address of this server
+func (server *InProcServer) URI() string {
+	if !server.started {
+		panic(errServerIsNotStarted)
+	}
+	return "inproc://" + server.name
+}
+
+// Handle registers the server under the name in uri
+func (server *InProcServer) Handle() (err error) {
+	if server.started {
+		return errServerIsAlreadyStarted
+	}
+	u, err := url.Parse(server.uri)
+	if err != nil {
+		return err
+	}
+	server.reopen()
+	server.name = u.Host
+	server.started = true
+	RegisterInProcService(server.name, &server.InProcService)
+	return nil
+}
+
+// Close unregisters the server
+func (server *InProcServer) Close() {
+	if server.started {
+		server.started = false
+		UnregisterInProcService(server.name)
+	}
+}
+
+// Shutdown the hprose in-process server gracefully, it unregisters the
+// server, releases the push subscribers, and waits for the in-flight
+// requests to finish until ctx is done.
+func (server *InProcServer) Shutdown(ctx gocontext.Context) error {
+	server.Close()
+	return server.drain(ctx)
+}
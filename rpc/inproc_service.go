@@ -0,0 +1,90 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/inproc_service.go                                  *
+ *                                                        *
+ * hprose in-process service for Go.                      *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+)
+
+// InProcService is the hprose in-process service, it is reached by the
+// clients in the same process without sockets.
+type InProcService struct {
+	baseService
+}
+
+// NewInProcService is the constructor of InProcService
+func NewInProcService() (service *InProcService) {
+	service = new(InProcService)
+	service.initInProcService()
+	return service
+}
+
+func (service *InProcService) initInProcService() {
+	service.initBaseService()
+	service.FixArguments = defaultFixArguments
+}
+
+type inprocHandler interface {
+	Service
+	Handle(request []byte, context Context) []byte
+}
+
+var inprocServices = make(map[string]inprocHandler)
+var inprocServicesLock sync.RWMutex
+
+// RegisterInProcService registers the service under name, then it can be
+// reached by NewClient("inproc://" + name). The service with the same name
+// is replaced.
+//
+// Any kind of service can be registered, such as *HTTPService or *TCPService,
+// but a server must be registered by its service, such as
+// &tcpServer.TCPService, since the Handle method of it is overridden.
+func RegisterInProcService(name string, service Service) {
+	handler, ok := service.(inprocHandler)
+	if !ok {
+		panic(errors.New("The service can't handle in-process requests"))
+	}
+	inprocServicesLock.Lock()
+	inprocServices[name] = handler
+	inprocServicesLock.Unlock()
+}
+
+// UnregisterInProcService removes the service registered under name
+func UnregisterInProcService(name string) {
+	inprocServicesLock.Lock()
+	delete(inprocServices, name)
+	inprocServicesLock.Unlock()
+}
+
+func getInProcService(uri string) (inprocHandler, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	inprocServicesLock.RLock()
+	service := inprocServices[u.Host]
+	inprocServicesLock.RUnlock()
+	if service == nil {
+		return nil, errors.New("No in-process service registered for " + uri)
+	}
+	return service, nil
+}
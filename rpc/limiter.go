@@ -12,14 +12,17 @@
  *                                                        *
  * hprose client requests limiter for Go.                 *
  *                                                        *
- * LastModified: Oct 2, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
 
 package rpc
 
-import "sync"
+import (
+	gocontext "context"
+	"sync"
+)
 
 type limiter struct {
 	cond                  sync.Cond
@@ -32,14 +35,26 @@ func (limiter *limiter) initLimiter() {
 	limiter.cond.L = &sync.Mutex{}
 }
 
-func (limiter *limiter) limit() {
-	for {
-		if limiter.requestCount < limiter.MaxConcurrentRequests {
-			break
+// limit waits until the request can be sent or ctx is done,
+// it must be called with the lock held.
+func (limiter *limiter) limit(ctx gocontext.Context) error {
+	var stop func()
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+	for limiter.requestCount >= limiter.MaxConcurrentRequests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if stop == nil {
+			stop = wakeOnDone(ctx, &limiter.cond)
 		}
 		limiter.cond.Wait()
 	}
 	limiter.requestCount++
+	return nil
 }
 
 func (limiter *limiter) unlimit() {
@@ -53,3 +68,24 @@ func (limiter *limiter) reset() {
 		limiter.cond.Signal()
 	}
 }
+
+// wakeOnDone wakes up all of the goroutines waiting on cond when ctx is done,
+// so they can return ctx.Err() instead of waiting for a signal.
+// The returned stop func doesn't wait, it can be called with the lock held.
+func wakeOnDone(ctx gocontext.Context, cond *sync.Cond) (stop func()) {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	quit := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			cond.L.Lock()
+			cond.Broadcast()
+			cond.L.Unlock()
+		case <-quit:
+		}
+	}()
+	return func() { close(quit) }
+}
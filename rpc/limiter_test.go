@@ -0,0 +1,50 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/limiter_test.go                                    *
+ *                                                        *
+ * hprose client requests limiter test for Go.            *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Context(t *testing.T) {
+	var l limiter
+	l.initLimiter()
+	l.MaxConcurrentRequests = 1
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	if err := l.limit(gocontext.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := gocontext.WithTimeout(
+		gocontext.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.limit(ctx); err != gocontext.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	if l.requestCount != 1 {
+		t.Error(l.requestCount)
+	}
+	l.unlimit()
+	if err := l.limit(gocontext.Background()); err != nil {
+		t.Error(err)
+	}
+}
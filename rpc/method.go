@@ -12,7 +12,7 @@
  *                                                        *
  * hprose method manager for Go.                          *
  *                                                        *
- * LastModified: Oct 23, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Options is the options of the published service method
@@ -32,6 +33,7 @@ type Options struct {
 	Oneway         bool
 	NameSpace      string
 	JSONCompatible bool
+	Timeout        time.Duration
 }
 
 // Method is the published service method
@@ -55,7 +57,9 @@ func (mm *methodManager) initMethodManager() {
 // AddFunction publish a func or bound method
 // name is the method name
 // function is a func or bound method
-// option includes Mode, Simple, Oneway and NameSpace
+// option includes Mode, Simple, Oneway, NameSpace and Timeout
+// if the first parameter of function is context.Context, it will be
+// cancelled when the client disconnects or the Timeout is reached
 func (mm *methodManager) AddFunction(
 	name string, function interface{}, option ...Options) {
 	if name == "" {
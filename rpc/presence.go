@@ -0,0 +1,93 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/presence.go                                        *
+ *                                                        *
+ * hprose push subscriber presence for Go.                *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"net/http"
+	"time"
+)
+
+// SubscriberInfo is the presence of a push subscriber
+type SubscriberInfo struct {
+	// Topic is the subscribed topic or pattern
+	Topic string
+	ID    string
+	// RemoteAddr is the address of the client, it is empty if the transport
+	// doesn't have it.
+	RemoteAddr string
+	// Header is the http header of the client over http or websocket
+	Header http.Header
+	// Identity is set by ServiceContext.SetIdentity when the client
+	// subscribes
+	Identity interface{}
+	// Metadata is attached by the client in InvokeSettings.Metadata
+	Metadata map[string]interface{}
+	// Native is true if the messages are pushed over the persistent
+	// connection, otherwise the client long polls the topic.
+	Native     bool
+	Subscribed time.Time
+	// LastSeen is the last time the client polls the topic or receives the
+	// messages natively.
+	LastSeen time.Time
+}
+
+// presence is the presence of a subscriber kept by the topic
+type presence struct {
+	remoteAddr string
+	header     http.Header
+	identity   interface{}
+	metadata   map[string]interface{}
+	subscribed time.Time
+	lastSeen   time.Time
+}
+
+// seen updates the presence when the client polls or subscribes the topic,
+// context is nil if the client is not seen by a request.
+func (p *presence) seen(context ServiceContext, metadata map[string]interface{}) {
+	p.lastSeen = time.Now()
+	if p.subscribed.IsZero() {
+		p.subscribed = p.lastSeen
+	}
+	if metadata != nil {
+		p.metadata = metadata
+	}
+	if context == nil {
+		return
+	}
+	if identity := context.Identity(); identity != nil {
+		p.identity = identity
+	}
+	switch context := context.(type) {
+	case *SocketContext:
+		p.remoteAddr = context.RemoteAddr().String()
+	case *WebSocketContext:
+		p.remoteAddr = context.Request.RemoteAddr
+		p.header = context.Request.Header.Clone()
+	case *HTTPContext:
+		p.remoteAddr = context.Request.RemoteAddr
+		p.header = context.Request.Header.Clone()
+	case *FastHTTPContext:
+		p.remoteAddr = context.RequestCtx.RemoteAddr().String()
+		p.header = make(http.Header)
+		context.RequestCtx.Request.Header.VisitAll(func(key, value []byte) {
+			p.header.Add(string(key), string(value))
+		})
+	}
+}
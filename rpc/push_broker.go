@@ -0,0 +1,163 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/push_broker.go                                     *
+ *                                                        *
+ * hprose push broker for Go.                             *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import "sync"
+
+// PushNode is a service attached to a PushBroker, it delivers the messages
+// published by the broker to its own subscribers.
+type PushNode interface {
+	// Deliver the message pushed to the topic to the subscribers of this
+	// node, ids is nil if the message is pushed to all of the subscribers.
+	// callback is called with the ids which the message is delivered to,
+	// it can be nil.
+	Deliver(topic string, ids []string, result interface{}, callback func([]string))
+	// Presence returns the ids of the subscribers of the topic on this node,
+	// the subscribers of the patterns matching the topic are included if
+	// match is true.
+	Presence(topic string, match bool) []string
+}
+
+// PushBroker distributes the messages pushed by a service to the subscribers
+// on all of the nodes attached to it.
+type PushBroker interface {
+	// Publish the message to the subscribers of the topic on all of the
+	// nodes, ids is nil if the message is pushed to all of the subscribers.
+	// callback is called once with the ids which the message is delivered
+	// to, it can be nil.
+	Publish(topic string, ids []string, result interface{}, callback func([]string))
+	// Subscribe attaches the node to the broker
+	Subscribe(node PushNode)
+	// Unsubscribe detaches the node from the broker
+	Unsubscribe(node PushNode)
+	// Presence returns the ids of the subscribers of the topic on all of
+	// the nodes.
+	Presence(topic string, match bool) []string
+}
+
+// MemoryBroker is the push broker of the services in the same process, it
+// is the default push broker of a service.
+type MemoryBroker struct {
+	nodes  []PushNode
+	locker sync.RWMutex
+}
+
+// NewMemoryBroker is the constructor of MemoryBroker
+func NewMemoryBroker() *MemoryBroker {
+	return new(MemoryBroker)
+}
+
+func (broker *MemoryBroker) getNodes() []PushNode {
+	broker.locker.RLock()
+	defer broker.locker.RUnlock()
+	return broker.nodes
+}
+
+// Subscribe attaches the node to the broker
+func (broker *MemoryBroker) Subscribe(node PushNode) {
+	broker.locker.Lock()
+	defer broker.locker.Unlock()
+	for _, n := range broker.nodes {
+		if n == node {
+			return
+		}
+	}
+	nodes := make([]PushNode, len(broker.nodes), len(broker.nodes)+1)
+	copy(nodes, broker.nodes)
+	broker.nodes = append(nodes, node)
+}
+
+// Unsubscribe detaches the node from the broker
+func (broker *MemoryBroker) Unsubscribe(node PushNode) {
+	broker.locker.Lock()
+	defer broker.locker.Unlock()
+	nodes := make([]PushNode, 0, len(broker.nodes))
+	for _, n := range broker.nodes {
+		if n != node {
+			nodes = append(nodes, n)
+		}
+	}
+	broker.nodes = nodes
+}
+
+// Publish the message to the subscribers of the topic on all of the nodes
+func (broker *MemoryBroker) Publish(
+	topic string, ids []string, result interface{}, callback func([]string)) {
+	nodes := broker.getNodes()
+	if len(nodes) == 0 {
+		if callback != nil {
+			callback(nil)
+		}
+		return
+	}
+	done := joinCallback(len(nodes), callback)
+	for _, node := range nodes {
+		node.Deliver(topic, ids, result, done)
+	}
+}
+
+// Presence returns the ids of the subscribers of the topic on all of the
+// nodes
+func (broker *MemoryBroker) Presence(topic string, match bool) []string {
+	var ids idSet
+	for _, node := range broker.getNodes() {
+		ids.add(node.Presence(topic, match))
+	}
+	return ids.list
+}
+
+// idSet is a list of the ids without duplicates
+type idSet struct {
+	list  []string
+	found map[string]bool
+}
+
+func (s *idSet) add(ids []string) {
+	if s.found == nil {
+		s.found = make(map[string]bool, len(ids))
+	}
+	for _, id := range ids {
+		if !s.found[id] {
+			s.found[id] = true
+			s.list = append(s.list, id)
+		}
+	}
+}
+
+// joinCallback returns the callback of n deliveries, callback is called
+// with all of the delivered ids after the returned callback is called n
+// times. It returns nil if callback is nil.
+func joinCallback(n int, callback func([]string)) func([]string) {
+	if callback == nil {
+		return nil
+	}
+	var locker sync.Mutex
+	sent := idSet{list: []string{}}
+	return func(ids []string) {
+		locker.Lock()
+		sent.add(ids)
+		n--
+		done := n == 0
+		locker.Unlock()
+		if done {
+			callback(sent.list)
+		}
+	}
+}
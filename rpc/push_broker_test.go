@@ -0,0 +1,110 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/push_broker_test.go                                *
+ *                                                        *
+ * hprose push broker test for Go.                        *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type testPushNode struct {
+	delivered []string
+	present   []string
+	messages  []interface{}
+}
+
+func (node *testPushNode) Deliver(
+	topic string, ids []string, result interface{}, callback func([]string)) {
+	node.messages = append(node.messages, result)
+	if callback != nil {
+		callback(node.delivered)
+	}
+}
+
+func (node *testPushNode) Presence(topic string, match bool) []string {
+	return node.present
+}
+
+func TestJoinCallback(t *testing.T) {
+	if joinCallback(2, nil) != nil {
+		t.Error("joinCallback should return nil if callback is nil")
+	}
+	var result []string
+	calls := 0
+	done := joinCallback(3, func(ids []string) {
+		calls++
+		result = ids
+	})
+	done([]string{"a", "b"})
+	done(nil)
+	if calls != 0 {
+		t.Fatal("callback is called before all of the deliveries")
+	}
+	done([]string{"b", "c"})
+	if calls != 1 || !reflect.DeepEqual(result, []string{"a", "b", "c"}) {
+		t.Error(calls, result)
+	}
+	done = joinCallback(1, func(ids []string) { result = ids })
+	done(nil)
+	if result == nil || len(result) != 0 {
+		t.Error("callback should be called with an empty list", result)
+	}
+}
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	var result []string
+	called := false
+	broker.Publish("news", nil, "hello", func(ids []string) {
+		called = true
+		result = ids
+	})
+	if !called || result != nil {
+		t.Fatal("callback should be called with nil without nodes", called, result)
+	}
+	node1 := &testPushNode{delivered: []string{"a"}, present: []string{"a", "b"}}
+	node2 := &testPushNode{delivered: []string{"c"}, present: []string{"b", "c"}}
+	broker.Subscribe(node1)
+	broker.Subscribe(node2)
+	broker.Subscribe(node1)
+	calls := 0
+	broker.Publish("news", nil, "hello", func(ids []string) {
+		calls++
+		result = ids
+	})
+	sort.Strings(result)
+	if calls != 1 || !reflect.DeepEqual(result, []string{"a", "c"}) {
+		t.Error(calls, result)
+	}
+	if len(node1.messages) != 1 || len(node2.messages) != 1 {
+		t.Error("the node is subscribed twice")
+	}
+	presence := broker.Presence("news", false)
+	sort.Strings(presence)
+	if !reflect.DeepEqual(presence, []string{"a", "b", "c"}) {
+		t.Error(presence)
+	}
+	broker.Unsubscribe(node1)
+	broker.Publish("news", nil, "world", nil)
+	if len(node1.messages) != 1 || len(node2.messages) != 2 {
+		t.Error("the node is not unsubscribed")
+	}
+}
@@ -0,0 +1,233 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/resolver.go                                        *
+ *                                                        *
+ * hprose service resolver for Go.                        *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver resolves the service addresses of a target, and pushes the
+// changes of them into the client
+type Resolver interface {
+	// Resolve returns the current service addresses
+	Resolve() ([]string, error)
+	// Watch calls update with the new service addresses when they are
+	// changed, until the resolver is closed
+	Watch(update func(uriList []string))
+	// Close stops watching
+	Close()
+}
+
+// Registrar announces the address of a server to a service registry
+type Registrar interface {
+	// Register is called when the server starts to handle requests
+	Register(uri string) error
+	// Deregister is called when the server is closed
+	Deregister(uri string) error
+}
+
+// ResolverFactory creates a Resolver for the target address
+type ResolverFactory func(target *url.URL) (Resolver, error)
+
+var resolverFactories = make(map[string]ResolverFactory)
+var resolverFactoriesLock sync.RWMutex
+
+// RegisterResolver registers a resolver factory for the scheme of target
+// addresses, such as "static+file" or "dns+srv"
+func RegisterResolver(scheme string, factory ResolverFactory) {
+	resolverFactoriesLock.Lock()
+	resolverFactories[strings.ToLower(scheme)] = factory
+	resolverFactoriesLock.Unlock()
+}
+
+func getResolverFactory(scheme string) ResolverFactory {
+	resolverFactoriesLock.RLock()
+	defer resolverFactoriesLock.RUnlock()
+	return resolverFactories[strings.ToLower(scheme)]
+}
+
+// resolvableClient is the client which can be updated by a resolver, the
+// clients created by the custom factories must embed a client of hprose.
+type resolvableClient interface {
+	Client
+	setResolver(resolver Resolver)
+	fireErrorEvent(name string, err error)
+}
+
+// NewClientWithResolver creates a client by the service addresses resolved
+// from target, the uri list of the client is updated when the resolved
+// addresses are changed, and the resolver is closed with the client.
+func NewClientWithResolver(target string) (client Client, err error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	factory := getResolverFactory(u.Scheme)
+	if factory == nil {
+		return nil, errors.New("No resolver for " + u.Scheme + " scheme.")
+	}
+	resolver, err := factory(u)
+	if err != nil {
+		return nil, err
+	}
+	uriList, err := resolver.Resolve()
+	if err == nil && len(uriList) == 0 {
+		err = errURIListEmpty
+	}
+	if err != nil {
+		resolver.Close()
+		return nil, err
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			resolver.Close()
+			client, err = nil, NewPanicError(e)
+		}
+	}()
+	client = NewClient(uriList...)
+	c, ok := client.(resolvableClient)
+	if !ok {
+		client.Close()
+		resolver.Close()
+		return nil, errResolverNotSupported
+	}
+	c.setResolver(resolver)
+	resolver.Watch(func(uriList []string) {
+		if len(uriList) == 0 {
+			return
+		}
+		// the bad addresses are reported, and the previous ones are kept
+		if err := updateURIList(c, uriList); err != nil {
+			c.fireErrorEvent(target, err)
+		}
+	})
+	return c, nil
+}
+
+// updateURIList sets the resolved addresses to the client, it returns the
+// error instead of panic if the addresses can't be used by the client.
+func updateURIList(client Client, uriList []string) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+		}
+	}()
+	scheme := checkAddresses(uriList, nil)
+	if u, _ := url.Parse(client.URI()); u != nil && u.Scheme != scheme {
+		return errNotSupportMultpleProtocol
+	}
+	client.SetURIList(uriList)
+	return nil
+}
+
+// pollingResolver calls resolve at every interval, and reports the changes
+type pollingResolver struct {
+	resolve  func() ([]string, error)
+	interval time.Duration
+	last     []string
+	done     chan struct{}
+	once     sync.Once
+	locker   sync.Mutex
+}
+
+func newPollingResolver(
+	resolve func() ([]string, error),
+	interval time.Duration) *pollingResolver {
+	return &pollingResolver{
+		resolve:  resolve,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// getInterval returns the interval query parameter of target, or def
+func getInterval(target *url.URL, def time.Duration) (time.Duration, error) {
+	if s := target.Query().Get("interval"); s != "" {
+		interval, err := time.ParseDuration(s)
+		if err != nil || interval <= 0 {
+			return 0, errors.New("Invalid interval: " + s)
+		}
+		return interval, nil
+	}
+	return def, nil
+}
+
+// Resolve returns the current service addresses
+func (r *pollingResolver) Resolve() ([]string, error) {
+	uriList, err := r.resolve()
+	if err != nil {
+		return nil, err
+	}
+	r.changed(uriList)
+	return uriList, nil
+}
+
+// changed returns true if uriList is different from the last resolved one
+func (r *pollingResolver) changed(uriList []string) bool {
+	sorted := make([]string, len(uriList))
+	copy(sorted, uriList)
+	sort.Strings(sorted)
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if len(sorted) == len(r.last) {
+		same := true
+		for i := range sorted {
+			if sorted[i] != r.last[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+	r.last = sorted
+	return true
+}
+
+// Watch the service addresses, the errors of resolving are ignored
+// and the last addresses are kept.
+func (r *pollingResolver) Watch(update func(uriList []string)) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				uriList, err := r.resolve()
+				if err == nil && len(uriList) > 0 && r.changed(uriList) {
+					update(uriList)
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops watching
+func (r *pollingResolver) Close() {
+	r.once.Do(func() { close(r.done) })
+}
@@ -0,0 +1,117 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/resolver_test.go                                   *
+ *                                                        *
+ * hprose resolver test for Go.                           *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"net/url"
+	"testing"
+)
+
+type testResolver struct {
+	uriList []string
+	update  func(uriList []string)
+	closed  bool
+}
+
+func (r *testResolver) Resolve() ([]string, error) {
+	if r.uriList != nil {
+		return r.uriList, nil
+	}
+	return []string{"tcp://127.0.0.1:1"}, nil
+}
+
+func (r *testResolver) Watch(update func(uriList []string)) {
+	r.update = update
+}
+
+func (r *testResolver) Close() {
+	r.closed = true
+}
+
+type testErrorEvent struct {
+	errors []error
+}
+
+func (e *testErrorEvent) OnError(name string, err error) {
+	e.errors = append(e.errors, err)
+}
+
+func TestNewClientWithResolver_BadAddresses(t *testing.T) {
+	resolver := new(testResolver)
+	RegisterResolver("test+bad", func(target *url.URL) (Resolver, error) {
+		return resolver, nil
+	})
+	client, err := NewClientWithResolver("test+bad://svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	event := new(testErrorEvent)
+	client.SetEvent(event)
+	bad := [][]string{
+		{"%zz://127.0.0.1:2"},
+		{"nope://127.0.0.1:2"},
+		{"tcp://127.0.0.1:2", "unix:/tmp/svc.sock"},
+		{"http://127.0.0.1:2"},
+	}
+	for _, uriList := range bad {
+		resolver.update(uriList)
+	}
+	if len(event.errors) != len(bad) {
+		t.Error(event.errors)
+	}
+	if uri := client.URI(); uri != "tcp://127.0.0.1:1" {
+		t.Error(uri)
+	}
+	resolver.update([]string{"tcp://127.0.0.1:2"})
+	if uri := client.URI(); uri != "tcp://127.0.0.1:2" {
+		t.Error(uri)
+	}
+}
+
+// testWrappedClient is created by a custom factory, it doesn't support
+// resolvers.
+type testWrappedClient struct {
+	Client
+	closed bool
+}
+
+func (c *testWrappedClient) Close() {
+	c.closed = true
+	c.Client.Close()
+}
+
+func TestNewClientWithResolver_CustomClient(t *testing.T) {
+	var client *testWrappedClient
+	RegisterClientFactory("test+wrapped", func(uri ...string) Client {
+		client = &testWrappedClient{Client: NewTCPClient("tcp://127.0.0.1:1")}
+		return client
+	})
+	resolver := &testResolver{uriList: []string{"test+wrapped://svc"}}
+	RegisterResolver("test+custom", func(target *url.URL) (Resolver, error) {
+		return resolver, nil
+	})
+	if _, err := NewClientWithResolver("test+custom://svc"); err != errResolverNotSupported {
+		t.Error(err)
+	}
+	if client == nil || !client.closed || !resolver.closed {
+		t.Error("the client or the resolver is not closed")
+	}
+}
@@ -0,0 +1,123 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/retry_policy.go                                    *
+ *                                                        *
+ * hprose retry policy for Go.                            *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const retryBudgetCapacity = 10
+
+// RetryPolicy is the retry policy of hprose client
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts including the first one,
+	// the Retry of InvokeSettings is used if it is not positive.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, the delay is doubled
+	// after every retry. The default value is 500ms.
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound of the delay. The default value is 5s.
+	MaxDelay time.Duration
+	// Jitter randomizes the delay by up to this fraction, from 0 to 1.
+	Jitter float64
+	// Retryable reports whether the failed request should be retried,
+	// if it is nil, only the idempotent requests are retried.
+	Retryable func(err error) bool
+	// Budget caps the retries to this fraction of the requests,
+	// 0 means no limit.
+	Budget      float64
+	tokens      float64
+	initialized bool
+	locker      sync.Mutex
+}
+
+func (policy *RetryPolicy) retryable(err error, context *ClientContext) bool {
+	if policy.Retryable != nil {
+		return policy.Retryable(err)
+	}
+	return context.Idempotent
+}
+
+func (policy *RetryPolicy) maxRetries(context *ClientContext) int {
+	if policy.MaxAttempts > 0 {
+		return policy.MaxAttempts - 1
+	}
+	return context.Retry
+}
+
+func (policy *RetryPolicy) delay(retried int) time.Duration {
+	base := policy.BaseDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	max := policy.MaxDelay
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	delay := base
+	for i := 1; i < retried && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if policy.Jitter > 0 {
+		jitter := policy.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay = time.Duration(float64(delay) * (1 + jitter*(2*rand.Float64()-1)))
+	}
+	return delay
+}
+
+// deposit is called for every request
+func (policy *RetryPolicy) deposit() {
+	if policy.Budget <= 0 {
+		return
+	}
+	policy.locker.Lock()
+	if !policy.initialized {
+		policy.tokens = retryBudgetCapacity
+		policy.initialized = true
+	}
+	policy.tokens += policy.Budget
+	if policy.tokens > retryBudgetCapacity {
+		policy.tokens = retryBudgetCapacity
+	}
+	policy.locker.Unlock()
+}
+
+// withdraw is called for every retry, it returns false if the budget is
+// exhausted
+func (policy *RetryPolicy) withdraw() bool {
+	if policy.Budget <= 0 {
+		return true
+	}
+	policy.locker.Lock()
+	defer policy.locker.Unlock()
+	if policy.tokens < 1 {
+		return false
+	}
+	policy.tokens--
+	return true
+}
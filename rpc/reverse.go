@@ -0,0 +1,230 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/reverse.go                                         *
+ *                                                        *
+ * hprose reverse invocation for Go.                      *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The ids of the requests sent by the server over a full duplex connection
+// have the highest bit set, the ids of the client requests don't. The old
+// clients may use any ids, so the server dispatches the responses of its
+// requests only after the client calls #reverse or #subscribe.
+const reverseFlag = 0x80000000
+
+func isReverseID(id uint32) bool {
+	return id&reverseFlag != 0
+}
+
+// ReverseService is the service published by a client, its methods are
+// invoked by the server over the full duplex socket or websocket connection
+// of the client, by the Client method of SocketContext or WebSocketContext.
+//
+// The server can invoke them only when the connection is open, it is opened
+// by the first request of the client. The client tells the server that it
+// handles the requests when it opens a connection after the ReverseService
+// is created, so the methods should be published before the invocations.
+type ReverseService struct {
+	baseService
+}
+
+func newReverseService() (service *ReverseService) {
+	service = new(ReverseService)
+	service.initBaseService()
+	service.FixArguments = defaultFixArguments
+	return service
+}
+
+// reverseServiceHolder creates the reverse service of a client lazily
+type reverseServiceHolder struct {
+	service *ReverseService
+	setup   func(service *ReverseService)
+	created int32
+	once    sync.Once
+}
+
+// ReverseService returns the service published by the client, which is
+// invoked by the server.
+func (holder *reverseServiceHolder) ReverseService() *ReverseService {
+	holder.once.Do(func() {
+		holder.service = newReverseService()
+		if holder.setup != nil {
+			holder.setup(holder.service)
+		}
+		atomic.StoreInt32(&holder.created, 1)
+	})
+	return holder.service
+}
+
+func (holder *reverseServiceHolder) isCreated() bool {
+	return atomic.LoadInt32(&holder.created) != 0
+}
+
+func (holder *reverseServiceHolder) handleReverse(request []byte) []byte {
+	service := holder.ReverseService()
+	return service.Handle(request, NewServiceContext(service, nil))
+}
+
+// reverseTransport sends the requests of the server to the client over a
+// connection, and receives the responses dispatched by the server.
+type reverseTransport struct {
+	send      func(id uint32, data []byte) error
+	available func() bool
+	onClose   []func()
+	uri       string
+	nextid    uint32
+	enabled   int32
+	responses map[uint32]chan socketResponse
+	err       error
+	client    *TransportClient
+	once      sync.Once
+	locker    sync.Mutex
+}
+
+func newReverseTransport(
+	uri string, send func(id uint32, data []byte) error) *reverseTransport {
+	return &reverseTransport{
+		send:      send,
+		uri:       uri,
+		responses: make(map[uint32]chan socketResponse),
+	}
+}
+
+// enable is called when the client tells that it handles the requests
+func (trans *reverseTransport) enable() {
+	atomic.StoreInt32(&trans.enabled, 1)
+}
+
+func (trans *reverseTransport) isEnabled() bool {
+	return atomic.LoadInt32(&trans.enabled) != 0
+}
+
+// isAvailable returns true if the remote client can handle the requests
+func (trans *reverseTransport) isAvailable() bool {
+	return trans.isEnabled() && (trans.available == nil || trans.available())
+}
+
+// addCloseHandler adds a handler called when the connection is closed,
+// it is called at once if the connection is already closed.
+func (trans *reverseTransport) addCloseHandler(handler func()) {
+	trans.locker.Lock()
+	if trans.err == nil {
+		trans.onClose = append(trans.onClose, handler)
+		trans.locker.Unlock()
+		return
+	}
+	trans.locker.Unlock()
+	handler()
+}
+
+// getClient returns the client which invokes the methods of the remote client
+func (trans *reverseTransport) getClient() Client {
+	trans.once.Do(func() {
+		client := new(TransportClient)
+		client.initBaseClient()
+		client.Transport = trans
+		client.SendAndReceive = trans.SendAndReceive
+		client.baseClient.SetURIList([]string{trans.uri})
+		trans.client = client
+	})
+	return trans.client
+}
+
+func (trans *reverseTransport) register(
+	id uint32, response chan socketResponse) error {
+	trans.locker.Lock()
+	defer trans.locker.Unlock()
+	if trans.err != nil {
+		return trans.err
+	}
+	trans.responses[id] = response
+	return nil
+}
+
+func (trans *reverseTransport) unregister(
+	id uint32) (response chan socketResponse) {
+	trans.locker.Lock()
+	response = trans.responses[id]
+	delete(trans.responses, id)
+	trans.locker.Unlock()
+	return
+}
+
+// dispatch the response of the client
+func (trans *reverseTransport) dispatch(id uint32, data []byte) {
+	if response := trans.unregister(id); response != nil {
+		response <- socketResponse{data, nil}
+	}
+}
+
+// fail the pending requests, and the later requests
+func (trans *reverseTransport) fail(err error) {
+	trans.locker.Lock()
+	if trans.err != nil {
+		trans.locker.Unlock()
+		return
+	}
+	trans.err = err
+	responses := trans.responses
+	trans.responses = nil
+	onClose := trans.onClose
+	trans.onClose = nil
+	trans.locker.Unlock()
+	for _, response := range responses {
+		response <- socketResponse{nil, err}
+	}
+	for _, handler := range onClose {
+		handler()
+	}
+}
+
+func (trans *reverseTransport) SendAndReceive(
+	data []byte, context *ClientContext) ([]byte, error) {
+	if !trans.isEnabled() {
+		return nil, errReverseNotEnabled
+	}
+	id := atomic.AddUint32(&trans.nextid, 1) | reverseFlag
+	response := make(chan socketResponse, 1)
+	if err := trans.register(id, response); err != nil {
+		return nil, err
+	}
+	if err := trans.send(id, data); err != nil {
+		trans.unregister(id)
+		return nil, err
+	}
+	ctx := context.Context()
+	timer := time.NewTimer(context.Timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-response:
+		return resp.data, resp.err
+	case <-timer.C:
+		trans.unregister(id)
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		trans.unregister(id)
+		return nil, ctx.Err()
+	}
+}
+
+// Close does nothing, the connection is closed by the client.
+func (trans *reverseTransport) Close() {}
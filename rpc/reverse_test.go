@@ -0,0 +1,83 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/reverse_test.go                                    *
+ *                                                        *
+ * hprose reverse invocation test for Go.                 *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestSocketService_ReverseIDBeforeNegotiation(t *testing.T) {
+	server := NewTCPServer("")
+	server.AddFunction("hello", func() string { return "hi" }, Options{})
+	if err := server.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	u, _ := url.Parse(server.URI())
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the old clients may send the requests with the highest bit of the ids
+	p := packet{fullDuplex: true, body: []byte(`Cs5"hello"z`)}
+	fromUint32(p.id[:], reverseFlag|1)
+	if err = sendData(conn, p); err != nil {
+		t.Fatal(err)
+	}
+	var data packet
+	if err = recvData(bufio.NewReader(conn), &data); err != nil {
+		t.Fatal(err)
+	}
+	if toUint32(data.id[:]) != reverseFlag|1 || string(data.body) != `Rs2"hi"z` {
+		t.Error(toUint32(data.id[:]), string(data.body))
+	}
+}
+
+func TestSocketContext_Client(t *testing.T) {
+	server := NewTCPServer("")
+	server.AddFunction("ask", func(q string, c *SocketContext) (string, error) {
+		var stub struct {
+			Answer func(string) (string, error)
+		}
+		c.Client().UseService(&stub)
+		return stub.Answer(q)
+	}, Options{})
+	if err := server.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	client.SetFullDuplex(true)
+	defer client.Close()
+	client.ReverseService().AddFunction("answer", func(q string) string {
+		return "answer of " + q
+	}, Options{})
+	var stub struct {
+		Ask func(string) (string, error)
+	}
+	client.UseService(&stub)
+	if r, err := stub.Ask("q"); err != nil || r != "answer of q" {
+		t.Error(r, err)
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose server for Go.                                  *
  *                                                        *
- * LastModified: Oct 5, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,9 +20,15 @@
 package rpc
 
 import (
+	gocontext "context"
+	"errors"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 )
 
 // Server interface
@@ -36,9 +42,92 @@ type Server interface {
 	Stop()
 }
 
+// NewServer is the constructor of Server, the type of the server is
+// selected by the scheme of uri, such as http, https, ws, wss, tcp, tcp4,
+// tcp6, unix, inproc and the schemes registered by RegisterServerFactory.
+func NewServer(uri string) Server {
+	u, err := url.Parse(uri)
+	if err != nil {
+		panic(err)
+	}
+	serverFactoriesLock.RLock()
+	newServer := serverFactories[strings.ToLower(u.Scheme)]
+	serverFactoriesLock.RUnlock()
+	if newServer == nil {
+		panic(errors.New("This server desn't support " + u.Scheme + " scheme."))
+	}
+	return newServer(uri)
+}
+
+// ServerFactory creates a Server listening on uri
+type ServerFactory func(uri string) Server
+
+var serverFactories = make(map[string]ServerFactory)
+var serverFactoriesLock sync.RWMutex
+
+// RegisterServerFactory registers the server factory for scheme, then
+// NewServer creates the server by it for the addresses of the scheme.
+//
+// The server created by the factory must start to serve in Handle without
+// blocking, stop accepting new requests in Close, and drain the in-flight
+// requests in Shutdown. URI returns the real address of the server which
+// can be used by NewClient.
+func RegisterServerFactory(scheme string, newServer ServerFactory) {
+	serverFactoriesLock.Lock()
+	serverFactories[strings.ToLower(scheme)] = newServer
+	serverFactoriesLock.Unlock()
+}
+
+func newHTTPServer(uri string) Server {
+	return NewHTTPServer(uri)
+}
+
+func newWebSocketServer(uri string) Server {
+	return NewWebSocketServer(uri)
+}
+
+func newTCPServer(uri string) Server {
+	return NewTCPServer(uri)
+}
+
+func newUnixServer(uri string) Server {
+	return NewUnixServer(uri)
+}
+
+func newInProcServer(uri string) Server {
+	return NewInProcServer(uri)
+}
+
+func init() {
+	RegisterServerFactory("http", newHTTPServer)
+	RegisterServerFactory("https", newHTTPServer)
+	RegisterServerFactory("ws", newWebSocketServer)
+	RegisterServerFactory("wss", newWebSocketServer)
+	RegisterServerFactory("tcp", newTCPServer)
+	RegisterServerFactory("tcp4", newTCPServer)
+	RegisterServerFactory("tcp6", newTCPServer)
+	RegisterServerFactory("unix", newUnixServer)
+	RegisterServerFactory("inproc", newInProcServer)
+}
+
 type starter struct {
-	server Server
-	c      chan os.Signal
+	// ShutdownTimeout is the max duration to wait for the in-flight requests
+	// when the server is stopped, the default value is 30 seconds.
+	ShutdownTimeout time.Duration
+	server          Server
+	c               chan os.Signal
+}
+
+// Starter implements Start, Restart and Stop of Server by Handle, Close
+// and Shutdown, the custom servers can embed it and initialize it by
+// InitStarter.
+type Starter struct {
+	starter
+}
+
+// InitStarter sets the server started by the starter
+func (starter *Starter) InitStarter(server Server) {
+	starter.server = server
 }
 
 // Start the hprose server
@@ -50,13 +139,23 @@ func (starter *starter) Start() (err error) {
 		starter.c = make(chan os.Signal, 1)
 		signal.Notify(starter.c, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL)
 		s := <-starter.c
-		starter.server.Close()
 		switch s {
 		case syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL:
 			signal.Stop(starter.c)
-			return
+			return starter.shutdown()
 		}
+		starter.server.Close()
+	}
+}
+
+func (starter *starter) shutdown() error {
+	timeout := starter.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
 	}
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), timeout)
+	defer cancel()
+	return starter.server.Shutdown(ctx)
 }
 
 // Restart the hprose server
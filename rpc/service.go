@@ -12,14 +12,17 @@
  *                                                        *
  * hprose service for Go.                                 *
  *                                                        *
- * LastModified: Oct 27, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
 
 package rpc
 
-import "time"
+import (
+	gocontext "context"
+	"time"
+)
 
 // Service interface
 type Service interface {
@@ -42,6 +45,9 @@ type Service interface {
 	AddBeforeFilterHandler(handler ...FilterHandler) Service
 	AddAfterFilterHandler(handler ...FilterHandler) Service
 	SetUserData(userdata map[string]interface{}) Service
-	Publish(topic string, timeout time.Duration, heartbeat time.Duration) Service
+	Publish(topic string, timeout time.Duration, heartbeat time.Duration, option ...TopicOptions) Service
+	Shutdown(ctx gocontext.Context) error
+	PushBroker() PushBroker
+	SetPushBroker(broker PushBroker) Service
 	Clients
 }
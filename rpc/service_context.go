@@ -12,13 +12,15 @@
  *                                                        *
  * hprose service context for Go.                         *
  *                                                        *
- * LastModified: Oct 6, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
 
 package rpc
 
+import gocontext "context"
+
 // ServiceContext is the hprose service context
 type ServiceContext interface {
 	Context
@@ -27,9 +29,13 @@ type ServiceContext interface {
 	Method() *Method
 	IsMissingMethod() bool
 	ByRef() bool
+	Context() gocontext.Context
+	Identity() interface{}
+	SetIdentity(identity interface{})
 	setMethod(method *Method)
 	setIsMissingMethod(value bool)
 	setByRef(value bool)
+	setContext(ctx gocontext.Context)
 }
 
 type serviceContext struct {
@@ -38,6 +44,8 @@ type serviceContext struct {
 	service         Service
 	isMissingMethod bool
 	byRef           bool
+	ctx             gocontext.Context
+	identity        interface{}
 }
 
 func (context *serviceContext) initServiceContext(service Service) {
@@ -46,6 +54,20 @@ func (context *serviceContext) initServiceContext(service Service) {
 	context.method = nil
 	context.isMissingMethod = false
 	context.byRef = false
+	context.ctx = gocontext.Background()
+	context.identity = nil
+}
+
+// NewServiceContext creates a ServiceContext for the custom transports
+// to call service.Handle, ctx is returned by the Context method of it.
+func NewServiceContext(
+	service Service, ctx gocontext.Context) ServiceContext {
+	context := new(serviceContext)
+	context.initServiceContext(service)
+	if ctx != nil {
+		context.ctx = ctx
+	}
+	return context
 }
 
 func (context *serviceContext) Method() *Method {
@@ -68,6 +90,24 @@ func (context *serviceContext) ByRef() bool {
 	return context.byRef
 }
 
+// Context returns the context.Context of the current invocation, it is
+// cancelled when the client disconnects or the method timeout is reached
+func (context *serviceContext) Context() gocontext.Context {
+	return context.ctx
+}
+
+// Identity returns the authenticated identity of the client
+func (context *serviceContext) Identity() interface{} {
+	return context.identity
+}
+
+// SetIdentity sets the authenticated identity of the client, it is usually
+// called by the handler which authenticates the request, and it is kept in
+// the presence of the push subscribers.
+func (context *serviceContext) SetIdentity(identity interface{}) {
+	context.identity = identity
+}
+
 func (context *serviceContext) setMethod(method *Method) {
 	context.method = method
 }
@@ -79,3 +119,7 @@ func (context *serviceContext) setIsMissingMethod(value bool) {
 func (context *serviceContext) setByRef(value bool) {
 	context.byRef = value
 }
+
+func (context *serviceContext) setContext(ctx gocontext.Context) {
+	context.ctx = ctx
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose service event for Go.                           *
  *                                                        *
- * LastModified: Sep 11, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -52,6 +52,14 @@ type subscribeEvent interface {
 	OnSubscribe(topic string, id string, service Service)
 }
 
+type subscribeEvent2 interface {
+	OnSubscribe(subscriber SubscriberInfo, service Service, context ServiceContext)
+}
+
 type unsubscribeEvent interface {
 	OnUnsubscribe(topic string, id string, service Service)
 }
+
+type unsubscribeEvent2 interface {
+	OnUnsubscribe(subscriber SubscriberInfo, service Service)
+}
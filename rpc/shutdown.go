@@ -0,0 +1,114 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/shutdown.go                                        *
+ *                                                        *
+ * hprose graceful shutdown for Go.                       *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainer tracks the in-flight requests of a service for graceful shutdown
+type drainer struct {
+	active  int64
+	closing chan struct{}
+	locker  sync.Mutex
+}
+
+type inflightCounter interface {
+	begin()
+	end()
+}
+
+func (d *drainer) initDrainer() {
+	d.closing = make(chan struct{})
+}
+
+func (d *drainer) begin() {
+	atomic.AddInt64(&d.active, 1)
+}
+
+func (d *drainer) end() {
+	atomic.AddInt64(&d.active, -1)
+}
+
+// closingChan returns a channel which is closed when the shutdown starts
+func (d *drainer) closingChan() <-chan struct{} {
+	d.locker.Lock()
+	defer d.locker.Unlock()
+	return d.closing
+}
+
+func (d *drainer) isClosing() bool {
+	select {
+	case <-d.closingChan():
+		return true
+	default:
+		return false
+	}
+}
+
+// reopen is called when the server is started again after shutdown
+func (d *drainer) reopen() {
+	d.locker.Lock()
+	select {
+	case <-d.closing:
+		d.closing = make(chan struct{})
+	default:
+	}
+	d.locker.Unlock()
+}
+
+func (d *drainer) drain(ctx gocontext.Context) error {
+	d.locker.Lock()
+	select {
+	case <-d.closing:
+	default:
+		close(d.closing)
+	}
+	d.locker.Unlock()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&d.active) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ShutdownHTTPServer gracefully shuts down the http server and the hprose
+// service served by it. The push subscribers of the service are released
+// first, so that their long-polling requests don't hold the http server
+// until the deadline of ctx.
+func ShutdownHTTPServer(
+	ctx gocontext.Context, server *http.Server, service Service) error {
+	errc := make(chan error, 1)
+	go func() { errc <- service.Shutdown(ctx) }()
+	err := server.Shutdown(ctx)
+	if e := <-errc; err == nil {
+		err = e
+	}
+	return err
+}
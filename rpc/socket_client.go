@@ -12,7 +12,7 @@
  *                                                        *
  * hprose socket client for Go.                           *
  *                                                        *
- * LastModified: Oct 21, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -22,6 +22,8 @@ package rpc
 import (
 	"crypto/tls"
 	"net"
+	"runtime"
+	"sync"
 	"time"
 )
 
@@ -38,19 +40,167 @@ type socketTransport interface {
 // SocketClient is base struct for TCPClient and UnixClient
 type SocketClient struct {
 	baseClient
-	socketTransport
+	reverseServiceHolder
 	ReadBuffer  int
 	WriteBuffer int
 	TLSConfig   *tls.Config
+	fullDuplex  bool
+	idleTimeout time.Duration
+	maxPoolSize int
+	createConn  func(uri string) net.Conn
+	transports  map[string]socketTransport
+	closed      bool
+	locker      sync.Mutex
 }
 
 func (client *SocketClient) initSocketClient() {
 	client.initBaseClient()
-	client.socketTransport = newHalfDuplexSocketTransport()
 	client.ReadBuffer = 0
 	client.WriteBuffer = 0
 	client.TLSConfig = nil
+	client.fullDuplex = false
+	client.idleTimeout = 0
+	client.maxPoolSize = runtime.NumCPU()
+	client.transports = make(map[string]socketTransport)
+	client.closed = false
 	client.SendAndReceive = client.sendAndReceive
+	client.nativePush = client.FullDuplex
+	client.setup = client.publishPush
+}
+
+func (client *SocketClient) setCreateConn(createConn func(uri string) net.Conn) {
+	client.createConn = createConn
+}
+
+// SetURIList set a list of server addresses,
+// the conn pools of the removed addresses are closed.
+func (client *SocketClient) SetURIList(uriList []string) {
+	client.baseClient.SetURIList(uriList)
+	uris := make(map[string]bool, len(uriList))
+	for _, uri := range uriList {
+		uris[uri] = true
+	}
+	client.locker.Lock()
+	for uri, trans := range client.transports {
+		if !uris[uri] {
+			trans.close()
+			delete(client.transports, uri)
+		}
+	}
+	client.locker.Unlock()
+}
+
+func (client *SocketClient) newTransport(uri string) (trans socketTransport) {
+	if client.fullDuplex {
+		fd := newFullDuplexSocketTransport()
+		fd.handleReverse = client.handleReverse
+		fd.reverseRequest = client.reverseRequest
+		fd.onConnClose = client.connClosed
+		trans = fd
+	} else {
+		trans = newHalfDuplexSocketTransport()
+	}
+	trans.SetIdleTimeout(client.idleTimeout)
+	trans.SetMaxPoolSize(client.maxPoolSize)
+	trans.setCreateConn(func() net.Conn { return client.createConn(uri) })
+	return
+}
+
+// reverseRequest returns nil if the ReverseService is not created
+func (client *SocketClient) reverseRequest() []byte {
+	if !client.isCreated() {
+		return nil
+	}
+	return client.baseClient.reverseRequest()
+}
+
+func (client *SocketClient) getTransport(uri string) socketTransport {
+	client.locker.Lock()
+	defer client.locker.Unlock()
+	if client.closed {
+		return nil
+	}
+	trans := client.transports[uri]
+	if trans == nil {
+		trans = client.newTransport(uri)
+		client.transports[uri] = trans
+	}
+	return trans
+}
+
+func (client *SocketClient) sendAndReceive(
+	data []byte, context *ClientContext) ([]byte, error) {
+	trans := client.getTransport(context.URI)
+	if trans == nil {
+		return nil, errClientIsAlreadyClosed
+	}
+	return trans.sendAndReceive(data, context)
+}
+
+func (client *SocketClient) connClosed() {
+	client.locker.Lock()
+	closed := client.closed
+	client.locker.Unlock()
+	if !closed {
+		client.resubscribe()
+	}
+}
+
+// IdleTimeout returns the conn pool idle timeout of hprose socket client
+func (client *SocketClient) IdleTimeout() time.Duration {
+	return client.idleTimeout
+}
+
+// SetIdleTimeout sets the conn pool idle timeout of hprose socket client
+func (client *SocketClient) SetIdleTimeout(timeout time.Duration) {
+	client.locker.Lock()
+	client.idleTimeout = timeout
+	for _, trans := range client.transports {
+		trans.SetIdleTimeout(timeout)
+	}
+	client.locker.Unlock()
+}
+
+// MaxPoolSize returns the max conn pool size of every service address
+func (client *SocketClient) MaxPoolSize() int {
+	return client.maxPoolSize
+}
+
+// SetMaxPoolSize sets the max conn pool size of every service address
+func (client *SocketClient) SetMaxPoolSize(size int) {
+	if size <= 0 {
+		return
+	}
+	client.locker.Lock()
+	client.maxPoolSize = size
+	for _, trans := range client.transports {
+		trans.SetMaxPoolSize(size)
+	}
+	client.locker.Unlock()
+}
+
+// FullDuplex returns the full duplex mode of hprose socket client
+func (client *SocketClient) FullDuplex() bool {
+	client.locker.Lock()
+	defer client.locker.Unlock()
+	return client.fullDuplex
+}
+
+// SetFullDuplex sets the full duplex mode of hprose socket client.
+//
+// In full duplex mode, many requests are multiplexed over a few connections,
+// the responses are dispatched by request id, so a request doesn't hold a
+// connection while it waits for the response.
+func (client *SocketClient) SetFullDuplex(fullDuplex bool) {
+	client.locker.Lock()
+	if client.fullDuplex != fullDuplex {
+		for uri, trans := range client.transports {
+			trans.close()
+			delete(client.transports, uri)
+		}
+		client.fullDuplex = fullDuplex
+	}
+	client.locker.Unlock()
 }
 
 // TLSClientConfig returns the tls.Config in hprose client
@@ -65,5 +215,12 @@ func (client *SocketClient) SetTLSClientConfig(config *tls.Config) {
 
 // Close the client
 func (client *SocketClient) Close() {
-	client.socketTransport.close()
+	client.baseClient.Close()
+	client.locker.Lock()
+	client.closed = true
+	for uri, trans := range client.transports {
+		trans.close()
+		delete(client.transports, uri)
+	}
+	client.locker.Unlock()
 }
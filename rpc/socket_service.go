@@ -12,7 +12,7 @@
  *                                                        *
  * hprose socket service for Go.                          *
  *                                                        *
- * LastModified: Oct 21, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -21,25 +21,41 @@ package rpc
 
 import (
 	"bufio"
+	gocontext "context"
 	"crypto/tls"
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // SocketContext is the hprose socket context for service
 type SocketContext struct {
 	serviceContext
 	net.Conn
+	reverse *reverseTransport
 }
 
 func (context *SocketContext) initSocketContext(
-	service Service, conn net.Conn) {
+	service Service, conn net.Conn, reverse *reverseTransport) {
 	context.initServiceContext(service)
 	context.Conn = conn
+	context.reverse = reverse
 	return
 }
 
+// Client returns a client which invokes the methods published by the
+// ReverseService of the remote client over this connection. The invocations
+// fail if the remote client is not in full duplex mode, or it hasn't created
+// the ReverseService before the connection is opened.
+func (context *SocketContext) Client() Client {
+	return context.reverse.getClient()
+}
+
+func (context *SocketContext) getReverse() *reverseTransport {
+	return context.reverse
+}
+
 func socketFixArguments(args []reflect.Value, context ServiceContext) {
 	i := len(args) - 1
 	switch args[i].Type() {
@@ -61,6 +77,8 @@ type SocketService struct {
 	baseService
 	TLSConfig   *tls.Config
 	contextPool sync.Pool
+	conns       map[net.Conn]struct{}
+	connsLock   sync.Mutex
 }
 
 func (service *SocketService) initSocketService() {
@@ -70,6 +88,34 @@ func (service *SocketService) initSocketService() {
 	}
 	service.FixArguments = socketFixArguments
 	service.TLSConfig = nil
+	service.conns = make(map[net.Conn]struct{})
+}
+
+// trackConn returns false if the service is shutting down
+func (service *SocketService) trackConn(conn net.Conn) bool {
+	service.connsLock.Lock()
+	defer service.connsLock.Unlock()
+	if service.isClosing() {
+		return false
+	}
+	service.conns[conn] = struct{}{}
+	return true
+}
+
+func (service *SocketService) untrackConn(conn net.Conn) {
+	service.connsLock.Lock()
+	delete(service.conns, conn)
+	service.connsLock.Unlock()
+}
+
+// closeConns closes all connections of the service
+func (service *SocketService) closeConns() {
+	service.connsLock.Lock()
+	for conn := range service.conns {
+		conn.Close()
+		delete(service.conns, conn)
+	}
+	service.connsLock.Unlock()
 }
 
 func (service *SocketService) acquireContext() (context *SocketContext) {
@@ -81,8 +127,21 @@ func (service *SocketService) releaseContext(context *SocketContext) {
 }
 
 func (service *SocketService) serveConn(conn net.Conn) {
+	if !service.trackConn(conn) {
+		conn.Close()
+		return
+	}
+	defer service.untrackConn(conn)
+	handler := new(connHandler)
+	handler.conn = conn
+	handler.reverse = newReverseTransport(
+		conn.RemoteAddr().Network()+"://"+conn.RemoteAddr().String(),
+		handler.sendReverse)
+	handler.reverse.available = func() bool {
+		return atomic.LoadInt32(&handler.fullDuplex) != 0
+	}
 	context := new(SocketContext)
-	context.initSocketContext(service, conn)
+	context.initSocketContext(service, conn, handler.reverse)
 	event := service.Event
 	defer func() {
 		if e := recover(); e != nil {
@@ -94,8 +153,6 @@ func (service *SocketService) serveConn(conn net.Conn) {
 		fireErrorEvent(event, err, context)
 		return
 	}
-	handler := new(connHandler)
-	handler.conn = conn
 	handler.serve(service)
 	if err := fireCloseEvent(event, context); err != nil {
 		fireErrorEvent(event, err, context)
@@ -150,38 +207,91 @@ func fireCloseEvent(event ServiceEvent, context *SocketContext) (err error) {
 
 type connHandler struct {
 	sync.Mutex
-	conn net.Conn
+	conn       net.Conn
+	ctx        gocontext.Context
+	fullDuplex int32
+	reverse    *reverseTransport
+}
+
+// sendReverse sends the request of the server to the client
+func (handler *connHandler) sendReverse(id uint32, data []byte) error {
+	if atomic.LoadInt32(&handler.fullDuplex) == 0 {
+		return errReverseNotSupported
+	}
+	p := packet{fullDuplex: true, body: data}
+	fromUint32(p.id[:], id)
+	handler.Lock()
+	err := sendData(handler.conn, p)
+	handler.Unlock()
+	return err
 }
 
 func (handler *connHandler) serve(service *SocketService) {
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	handler.ctx = ctx
 	reader := bufio.NewReader(handler.conn)
-	var data packet
+	// half duplex requests are handled in order, but not in the
+	// receive loop, so that the disconnection can be detected
+	var prev chan struct{}
 	for {
+		var data packet
 		if err := recvData(reader, &data); err != nil {
 			break
 		}
 		if data.fullDuplex {
-			go handler.handle(service, data)
+			atomic.StoreInt32(&handler.fullDuplex, 1)
+			id := toUint32(data.id[:])
+			if isReverseID(id) && handler.reverse.isEnabled() {
+				handler.reverse.dispatch(id, data.body)
+				continue
+			}
+		}
+		// the requests received after the shutdown starts are rejected,
+		// or the clients which keep sending hold the shutdown
+		closing := service.isClosing()
+		if !closing {
+			service.begin()
+		}
+		if data.fullDuplex {
+			go handler.handle(service, data, closing)
 		} else {
-			handler.handle(service, data)
+			wait, done := prev, make(chan struct{})
+			prev = done
+			go func() {
+				if wait != nil {
+					<-wait
+				}
+				handler.handle(service, data, closing)
+				close(done)
+			}()
 		}
 	}
+	cancel()
+	handler.reverse.fail(errConnIsClosed)
+	if prev != nil {
+		<-prev
+	}
 	handler.conn.Close()
 }
 
-func (handler *connHandler) handle(service *SocketService, data packet) {
+func (handler *connHandler) handle(
+	service *SocketService, data packet, closing bool) {
 	context := service.acquireContext()
-	context.initSocketContext(service, handler.conn)
-	data.body = service.Handle(data.body, context)
-	if data.fullDuplex {
-		handler.Lock()
+	context.initSocketContext(service, handler.conn, handler.reverse)
+	context.setContext(handler.ctx)
+	if closing {
+		data.body = service.endError(errServiceIsShuttingDown, context)
+	} else {
+		data.body = service.Handle(data.body, context)
 	}
+	handler.Lock()
 	err := sendData(handler.conn, data)
-	if data.fullDuplex {
-		handler.Unlock()
-	}
+	handler.Unlock()
 	if err != nil {
 		fireErrorEvent(service.Event, err, context)
 	}
 	service.releaseContext(context)
+	if !closing {
+		service.end()
+	}
 }
@@ -0,0 +1,165 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/stdio_client.go                                    *
+ *                                                        *
+ * hprose stdio client for Go.                            *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// processConn is a net.Conn over the stdin and stdout of a child process
+type processConn struct {
+	cmd    *exec.Cmd
+	stdin  *os.File
+	stdout *os.File
+	once   sync.Once
+}
+
+func (conn *processConn) Read(b []byte) (int, error) {
+	return conn.stdout.Read(b)
+}
+
+func (conn *processConn) Write(b []byte) (int, error) {
+	return conn.stdin.Write(b)
+}
+
+// Close closes the stdin of the child process, and kills it if it doesn't
+// exit in a second.
+func (conn *processConn) Close() error {
+	conn.once.Do(func() {
+		conn.stdin.Close()
+		conn.stdout.Close()
+		go func() {
+			done := make(chan struct{})
+			go func() {
+				conn.cmd.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				conn.cmd.Process.Kill()
+			}
+		}()
+	})
+	return nil
+}
+
+func (conn *processConn) LocalAddr() net.Addr {
+	return stdioAddr("stdio")
+}
+
+func (conn *processConn) RemoteAddr() net.Addr {
+	return stdioAddr(conn.cmd.Path)
+}
+
+func (conn *processConn) SetDeadline(t time.Time) error {
+	if err := conn.stdout.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return conn.stdin.SetWriteDeadline(t)
+}
+
+func (conn *processConn) SetReadDeadline(t time.Time) error {
+	return conn.stdout.SetReadDeadline(t)
+}
+
+func (conn *processConn) SetWriteDeadline(t time.Time) error {
+	return conn.stdin.SetWriteDeadline(t)
+}
+
+// StdioClient is hprose client which spawns a child process, and talks to
+// the StdioService served on its stdin and stdout.
+//
+// The address is the path of the program, and the arguments are the arg
+// query parameters, such as "stdio:///usr/local/bin/plugin?arg=-v". The
+// process is started by the first request, and it is restarted by the next
+// request after it crashed or exited.
+//
+// The client works in full duplex mode with one process for every address
+// by default, SetMaxPoolSize sets the max count of the processes.
+type StdioClient struct {
+	SocketClient
+	// Command creates the command of the child process for uri,
+	// the stdin and stdout of the command are set by the client.
+	Command func(uri string) *exec.Cmd
+}
+
+// NewStdioClient is the constructor of StdioClient
+func NewStdioClient(uri ...string) (client *StdioClient) {
+	client = new(StdioClient)
+	client.initSocketClient()
+	client.fullDuplex = true
+	client.maxPoolSize = 1
+	client.Command = defaultStdioCommand
+	client.setCreateConn(client.createStdioConn)
+	client.SetURIList(uri)
+	return
+}
+
+func newStdioClient(uri ...string) Client {
+	return NewStdioClient(uri...)
+}
+
+// SetURIList set a list of server addresses
+func (client *StdioClient) SetURIList(uriList []string) {
+	checkAddresses(uriList, stdioSchemes)
+	client.SocketClient.SetURIList(uriList)
+}
+
+func defaultStdioCommand(uri string) *exec.Cmd {
+	u, err := url.Parse(uri)
+	ifErrorPanic(err)
+	path := u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+	return exec.Command(path, u.Query()["arg"]...)
+}
+
+func (client *StdioClient) createStdioConn(uri string) net.Conn {
+	cmd := client.Command(uri)
+	stdinReader, stdinWriter, err := os.Pipe()
+	ifErrorPanic(err)
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		stdinWriter.Close()
+		panic(err)
+	}
+	cmd.Stdin = stdinReader
+	cmd.Stdout = stdoutWriter
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	err = cmd.Start()
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdinWriter.Close()
+		stdoutReader.Close()
+		panic(err)
+	}
+	return &processConn{cmd: cmd, stdin: stdinWriter, stdout: stdoutReader}
+}
@@ -0,0 +1,98 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/stdio_service.go                                   *
+ *                                                        *
+ * hprose stdio service for Go.                           *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+type stdioAddr string
+
+func (addr stdioAddr) Network() string {
+	return "stdio"
+}
+
+func (addr stdioAddr) String() string {
+	return string(addr)
+}
+
+// stdioConn is a net.Conn over a pair of io.Reader and io.Writer
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (conn *stdioConn) Close() error {
+	var err error
+	if closer, ok := conn.Reader.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if closer, ok := conn.Writer.(io.Closer); ok {
+		if e := closer.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (conn *stdioConn) LocalAddr() net.Addr {
+	return stdioAddr("stdout")
+}
+
+func (conn *stdioConn) RemoteAddr() net.Addr {
+	return stdioAddr("stdin")
+}
+
+func (conn *stdioConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *stdioConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *stdioConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// StdioService is the hprose service over a pair of io.Reader and io.Writer,
+// such as os.Stdin and os.Stdout of a subprocess plugin. The data is framed
+// the same as the socket service.
+type StdioService struct {
+	SocketService
+}
+
+// NewStdioService is the constructor of StdioService
+func NewStdioService() (service *StdioService) {
+	service = new(StdioService)
+	service.initSocketService()
+	return service
+}
+
+// ServeStdio serves the requests read from reader, and writes the responses
+// to writer. ServeStdio blocks until reader is closed or returns an error.
+//
+// Nothing else may be written to writer, so a plugin should write its logs
+// to os.Stderr when it serves on os.Stdout.
+func (service *StdioService) ServeStdio(reader io.Reader, writer io.Writer) {
+	service.serveConn(&stdioConn{reader, writer})
+}
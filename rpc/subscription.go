@@ -0,0 +1,121 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/subscription.go                                    *
+ *                                                        *
+ * hprose push subscription for Go.                       *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Subscription is a push topic subscription which delivers the messages
+// into a channel, it is returned by Client.SubscribeChan.
+//
+// Usage:
+//
+//	var news chan string
+//	sub, err := client.SubscribeChan("news", "", nil, &news)
+//	if err != nil {
+//		panic(err)
+//	}
+//	for msg := range news {
+//		fmt.Println(msg)
+//	}
+//	if err := sub.Err(); err != nil {
+//		fmt.Println("subscription failed:", err)
+//	}
+type Subscription struct {
+	client      *baseClient
+	name        string
+	id          string
+	channel     reflect.Value
+	resultTypes []reflect.Type
+	err         error
+	closed      bool
+	done        chan struct{}
+	once        sync.Once
+	locker      sync.RWMutex
+}
+
+func newSubscription(
+	client *baseClient, name string, id string,
+	channel reflect.Value) *Subscription {
+	return &Subscription{
+		client:      client,
+		name:        name,
+		id:          id,
+		channel:     channel,
+		resultTypes: []reflect.Type{channel.Type().Elem()},
+		done:        make(chan struct{}),
+	}
+}
+
+// Topic returns the subscribed topic name
+func (s *Subscription) Topic() string {
+	return s.name
+}
+
+// ID returns the subscriber id
+func (s *Subscription) ID() string {
+	return s.id
+}
+
+// Err returns the error which closes the subscription, it is nil if the
+// subscription is active or closed by Close.
+func (s *Subscription) Err() error {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	return s.err
+}
+
+// Close unsubscribes the topic and closes the channel, the topic is still
+// subscribed if there are other callbacks or channels for it.
+func (s *Subscription) Close() {
+	if topic := s.client.getTopic(s.name, s.id); topic != nil {
+		if topic.removeSubscription(s) {
+			s.client.Unsubscribe(s.name, s.id)
+		}
+	}
+	s.close(nil)
+}
+
+// close the channel with err, it waits for the message being sent.
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.locker.Lock()
+		s.err = err
+		s.closed = true
+		s.channel.Close()
+		s.locker.Unlock()
+	})
+}
+
+// send the message into the channel, it is the callback of the topic.
+func (s *Subscription) send(results []reflect.Value, err error) {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	if s.closed {
+		return
+	}
+	reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: s.channel, Send: results[0]},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.done)},
+	})
+}
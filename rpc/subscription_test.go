@@ -0,0 +1,122 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/subscription_test.go                               *
+ *                                                        *
+ * hprose push subscription test for Go.                  *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSubscriptionServer(t *testing.T) *TCPServer {
+	server := NewTCPServer("")
+	server.Publish("news", time.Second, time.Second)
+	if err := server.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestSubscription_Close(t *testing.T) {
+	server := newTestSubscriptionServer(t)
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	var news chan string
+	s, err := client.SubscribeChan("news", "c1", nil, &news)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !server.Exist("news", "c1") {
+		if time.Now().After(deadline) {
+			t.Fatal("the topic is not subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	server.Push("news", "hello")
+	select {
+	case msg := <-news:
+		if msg != "hello" {
+			t.Error(msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the message is not received")
+	}
+	s.Close()
+	if _, ok := <-news; ok {
+		t.Error("the channel is not closed")
+	}
+	if s.Err() != nil {
+		t.Error(s.Err())
+	}
+}
+
+func TestSubscription_Err(t *testing.T) {
+	server := newTestSubscriptionServer(t)
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	var weather chan string
+	s, err := client.SubscribeChan("weather", "c1", nil, &weather)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, ok := <-weather:
+		if ok {
+			t.Fatal("no message is pushed to the topic")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the channel is not closed")
+	}
+	if s.Err() == nil {
+		t.Error("Err should return the error of the unpublished topic")
+	}
+}
+
+type testCountErrorEvent struct {
+	count int32
+}
+
+func (e *testCountErrorEvent) OnError(name string, err error) {
+	atomic.AddInt32(&e.count, 1)
+}
+
+func TestSubscribe_FailureBackoff(t *testing.T) {
+	server := newTestSubscriptionServer(t)
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	event := new(testCountErrorEvent)
+	client.SetEvent(event)
+	client.Subscribe("weather", "c1", nil, func(string) {})
+	// the unpublished topic is subscribed again after 500ms
+	time.Sleep(700 * time.Millisecond)
+	if n := atomic.LoadInt32(&event.count); n < 1 || n > 3 {
+		t.Error("the failed topic is subscribed again", n, "times")
+	}
+	client.Close()
+	time.Sleep(100 * time.Millisecond)
+	n := atomic.LoadInt32(&event.count)
+	time.Sleep(time.Second)
+	if m := atomic.LoadInt32(&event.count); m != n {
+		t.Error("the topic is subscribed after the client is closed", m-n)
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose tcp client for Go.                              *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -54,11 +54,11 @@ func newTCPClient(uri ...string) Client {
 // SetURIList set a list of server addresses
 func (client *TCPClient) SetURIList(uriList []string) {
 	checkAddresses(uriList, tcpSchemes)
-	client.baseClient.SetURIList(uriList)
+	client.SocketClient.SetURIList(uriList)
 }
 
-func (client *TCPClient) createTCPConn() net.Conn {
-	u, err := url.Parse(client.uri)
+func (client *TCPClient) createTCPConn(uri string) net.Conn {
+	u, err := url.Parse(uri)
 	ifErrorPanic(err)
 	tcpaddr, err := net.ResolveTCPAddr(u.Scheme, u.Host)
 	ifErrorPanic(err)
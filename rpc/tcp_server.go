@@ -12,7 +12,7 @@
  *                                                        *
  * hprose tcp server for Go.                              *
  *                                                        *
- * LastModified: Oct 2, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"net"
 	"net/url"
 )
@@ -28,8 +29,11 @@ import (
 type TCPServer struct {
 	TCPService
 	starter
-	uri      string
-	listener *net.TCPListener
+	// Registrar announces the server address when Handle starts,
+	// and withdraws it when Close
+	Registrar Registrar
+	uri       string
+	listener  *net.TCPListener
 }
 
 // NewTCPServer is the constructor for TCPServer
@@ -61,6 +65,7 @@ func (server *TCPServer) Handle() (err error) {
 	if server.listener != nil {
 		return errServerIsAlreadyStarted
 	}
+	server.reopen()
 	u, err := url.Parse(server.uri)
 	if err != nil {
 		return err
@@ -72,6 +77,13 @@ func (server *TCPServer) Handle() (err error) {
 	if server.listener, err = net.ListenTCP(u.Scheme, addr); err != nil {
 		return err
 	}
+	if server.Registrar != nil {
+		if err = server.Registrar.Register(server.URI()); err != nil {
+			server.listener.Close()
+			server.listener = nil
+			return err
+		}
+	}
 	go server.ServeTCP(server.listener)
 	return nil
 }
@@ -79,8 +91,23 @@ func (server *TCPServer) Handle() (err error) {
 // Close the hprose tcp server
 func (server *TCPServer) Close() {
 	if server.listener != nil {
+		if server.Registrar != nil {
+			if err := server.Registrar.Deregister(server.URI()); err != nil {
+				fireErrorEvent(server.Event, err, nil)
+			}
+		}
 		listener := server.listener
 		server.listener = nil
 		listener.Close()
 	}
 }
+
+// Shutdown the hprose tcp server gracefully, it stops accepting new
+// connections, waits for the in-flight requests to finish until ctx is done,
+// releases the push subscribers, and then closes all connections.
+func (server *TCPServer) Shutdown(ctx gocontext.Context) error {
+	server.Close()
+	err := server.drain(ctx)
+	server.closeConns()
+	return err
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose push topic for Go.                              *
  *                                                        *
- * LastModified: Sep 21, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -24,53 +24,508 @@ import (
 	"time"
 )
 
+// OverflowPolicy decides what to do with a message pushed to a subscriber
+// whose queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber receives the queued messages,
+	// the waiting messages are queued in order when the queue has room, and
+	// they are dropped if the subscriber goes offline.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued message
+	OverflowDropOldest
+	// OverflowDropNewest drops the pushed message
+	OverflowDropNewest
+	// OverflowDisconnect drops the subscriber with its queued messages
+	OverflowDisconnect
+)
+
+// TopicOptions is the options of a push topic
+type TopicOptions struct {
+	// QueueSize is the max number of queued messages of every subscriber,
+	// the default is 1.
+	QueueSize int
+	Overflow  OverflowPolicy
+	// Batch delivers all of the queued messages in a list at once, so the
+	// callback of the client should take a slice.
+	Batch bool
+	// Retain is the number of the latest messages kept for the clients to
+	// resume from a sequence number, 0 means no message is kept.
+	Retain int
+}
+
+// QueueStats is the queue metrics of a push subscriber
+type QueueStats struct {
+	ID     string
+	Length int
+	// Blocked is the number of the messages waiting for the room of the
+	// queue when the overflow policy is OverflowBlock
+	Blocked   int
+	Unacked   int
+	Delivered uint64
+	Dropped   uint64
+}
+
+type pushMessage struct {
+	seq      int64
+	prev     int64
+	data     interface{}
+	callback func(bool)
+}
+
+func (m pushMessage) done(ok bool) {
+	if m.callback != nil {
+		m.callback(ok)
+	}
+}
+
+func doneMessages(messages []pushMessage, ok bool) {
+	for _, m := range messages {
+		m.done(ok)
+	}
+}
+
+type subscriber struct {
+	presence
+	id    string
+	queue []pushMessage
+	// blocked is the messages waiting for the room of the queue when the
+	// overflow policy is OverflowBlock
+	blocked []pushMessage
+	// unacked is the messages polled by the client which are not
+	// acknowledged yet
+	unacked []pushMessage
+	// lastSeq is the sequence number of the last message sent to the
+	// subscriber
+	lastSeq int64
+	signal  chan struct{}
+	native  *reverseTransport
+	// sending is true when a goroutine pushes the queue over native
+	sending   bool
+	polls     int
+	timer     *time.Timer
+	delivered uint64
+	dropped   uint64
+}
+
+// append the message to the queue, it must be called with the topic lock
+// held.
+func (s *subscriber) append(m pushMessage) {
+	m.prev = s.lastSeq
+	s.lastSeq = m.seq
+	s.queue = append(s.queue, m)
+}
+
+// notify the waiters that the subscriber has changed,
+// it must be called with the topic lock held.
+func (s *subscriber) notify() {
+	close(s.signal)
+	s.signal = make(chan struct{})
+}
+
+func (s *subscriber) stopTimer() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// retainedMessage is kept for the clients to resume
+type retainedMessage struct {
+	seq  int64
+	data interface{}
+	// ids is nil if the message is pushed to all of the subscribers
+	ids []string
+}
+
+func (m *retainedMessage) isPushedTo(id string) bool {
+	if m.ids == nil {
+		return true
+	}
+	for _, i := range m.ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
 type topic struct {
 	sync.RWMutex
-	messages  map[string]chan interface{}
-	heartbeat time.Duration
+	// name is the subscribed topic or pattern
+	name    string
+	pattern bool
+	// dynamic is true if the topic is created by the subscriptions, it is
+	// removed when it has no subscriber
+	dynamic     bool
+	subscribers map[string]*subscriber
+	timeout     time.Duration
+	heartbeat   time.Duration
+	options     TopicOptions
+	seq         int64
+	retained    []retainedMessage
+	// lost is the sequence number of the last message which is not retained
+	lost int64
+	// expire is called when a long polling subscriber doesn't come back
+	// in the heartbeat
+	expire func(s *subscriber)
 }
 
-func newTopic(heartbeat time.Duration) *topic {
+func newTopic(
+	name string, timeout time.Duration,
+	heartbeat time.Duration, options TopicOptions) *topic {
 	t := new(topic)
-	t.messages = make(map[string]chan interface{})
+	t.name = name
+	t.pattern = isTopicPattern(name)
+	t.subscribers = make(map[string]*subscriber)
+	t.timeout = timeout
 	t.heartbeat = heartbeat
+	if options.QueueSize <= 0 {
+		options.QueueSize = 1
+	}
+	t.options = options
 	return t
 }
 
-func (t *topic) get(id string) (message chan interface{}) {
+func (t *topic) get(id string) (s *subscriber) {
 	t.RLock()
-	message = t.messages[id]
+	s = t.subscribers[id]
 	t.RUnlock()
 	return
 }
 
-func (t *topic) put(id string, message chan interface{}) {
+// subscribe returns true if the subscriber is created,
+// it must be called with the lock held.
+func (t *topic) subscribe(id string) (s *subscriber, created bool) {
+	s = t.subscribers[id]
+	if s == nil {
+		s = &subscriber{id: id, signal: make(chan struct{})}
+		t.subscribers[id] = s
+		created = true
+	}
+	return
+}
+
+// publish returns the sequence number of the message pushed to ids,
+// ids is nil if the message is pushed to all of the subscribers.
+func (t *topic) publish(data interface{}, ids []string) int64 {
+	t.Lock()
+	defer t.Unlock()
+	t.seq++
+	if t.options.Retain <= 0 {
+		t.lost = t.seq
+		return t.seq
+	}
+	if len(t.retained) == t.options.Retain {
+		t.lost = t.retained[0].seq
+		copy(t.retained, t.retained[1:])
+		t.retained = t.retained[:len(t.retained)-1]
+	}
+	if ids != nil {
+		ids = append([]string(nil), ids...)
+	}
+	t.retained = append(t.retained, retainedMessage{t.seq, data, ids})
+	return t.seq
+}
+
+// resume the subscriber from ack, the sequence number of the last message
+// received by the client. The retained messages after ack are queued if the
+// subscriber is created, otherwise the unacknowledged messages after ack are
+// queued again. It returns the acknowledged messages, and it must be called
+// with the lock held.
+func (t *topic) resume(
+	s *subscriber, created bool, ack int64) (acked []pushMessage) {
+	if created {
+		if ack <= 0 || ack > t.seq {
+			// the client hasn't received any message of this topic
+			return nil
+		}
+		prev := ack
+		if t.lost > ack {
+			prev = t.lost
+		}
+		var replay []pushMessage
+		for i := range t.retained {
+			m := &t.retained[i]
+			if m.seq > ack && m.isPushedTo(s.id) {
+				replay = append(replay, pushMessage{seq: m.seq, prev: prev, data: m.data})
+				prev = m.seq
+			}
+		}
+		s.queue = append(replay, s.queue...)
+		s.lastSeq = prev
+		return nil
+	}
+	n := 0
+	for n < len(s.unacked) && s.unacked[n].seq <= ack {
+		n++
+	}
+	acked = s.unacked[:n]
+	s.delivered += uint64(n)
+	s.queue = append(s.unacked[n:len(s.unacked):len(s.unacked)], s.queue...)
+	s.unacked = nil
+	return
+}
+
+// beginPoll returns the long polling subscriber of the id, the client
+// acknowledges the messages until ack if it is sequenced.
+func (t *topic) beginPoll(
+	id string, sequenced bool, ack int64,
+	context ServiceContext, metadata map[string]interface{}) (
+	s *subscriber, created bool, acked []pushMessage) {
+	t.Lock()
+	s, created = t.subscribe(id)
+	s.seen(context, metadata)
+	if sequenced {
+		acked = t.resume(s, created, ack)
+	}
+	s.native = nil
+	s.polls++
+	s.stopTimer()
+	t.Unlock()
+	return
+}
+
+// endPoll starts the heartbeat timer of the subscriber
+func (t *topic) endPoll(s *subscriber) {
+	t.Lock()
+	s.polls--
+	if s.polls == 0 && s.native == nil && t.subscribers[s.id] == s {
+		s.stopTimer()
+		s.timer = time.AfterFunc(t.heartbeat, func() { t.expire(s) })
+	}
+	t.Unlock()
+}
+
+// isPolling returns false if the subscriber is removed or it has switched
+// to native push
+func (t *topic) isPolling(s *subscriber) bool {
+	t.RLock()
+	defer t.RUnlock()
+	return s.native == nil && t.subscribers[s.id] == s
+}
+
+// isIdle returns true if the long polling subscriber doesn't come back
+func isIdle(s *subscriber) bool {
+	return s.polls == 0 && s.native == nil
+}
+
+// subscribeNative returns true if the subscriber is created, and true if
+// a goroutine should be started to send the queue.
+func (t *topic) subscribeNative(
+	id string, reverse *reverseTransport, ack int64,
+	context ServiceContext, metadata map[string]interface{}) (
+	s *subscriber, created bool, send bool, acked []pushMessage) {
+	t.Lock()
+	s, created = t.subscribe(id)
+	s.seen(context, metadata)
+	acked = t.resume(s, created, ack)
+	s.native = reverse
+	s.stopTimer()
+	send = len(s.queue) > 0 && !s.sending
+	if send {
+		s.sending = true
+	}
+	s.notify()
+	t.Unlock()
+	return
+}
+
+// take the messages which should be delivered in one push,
+// it must be called with the lock held.
+func (t *topic) take(s *subscriber) (messages []pushMessage) {
+	n := len(s.queue)
+	if n == 0 {
+		return nil
+	}
+	if !t.options.Batch {
+		n = 1
+	}
+	messages = make([]pushMessage, n)
+	copy(messages, s.queue)
+	s.queue = s.queue[n:]
+	t.unblock(s)
+	s.notify()
+	return
+}
+
+// unblock moves the blocked messages into the queue in order while it has
+// room, it must be called with the lock held.
+func (t *topic) unblock(s *subscriber) {
+	for len(s.blocked) > 0 && len(s.queue) < t.options.QueueSize {
+		s.append(s.blocked[0])
+		s.blocked = s.blocked[1:]
+	}
+}
+
+// poll takes the queued messages of the long polling subscriber, they are
+// delivered when the client acknowledges them if it is sequenced.
+func (t *topic) poll(
+	s *subscriber, sequenced bool) (messages []pushMessage, signal chan struct{}) {
+	t.Lock()
+	messages = t.take(s)
+	if sequenced {
+		s.unacked = append(s.unacked, messages...)
+	} else {
+		s.delivered += uint64(len(messages))
+	}
+	signal = s.signal
+	t.Unlock()
+	return
+}
+
+// sequencedPayload returns the data pushed to the client with the sequence
+// numbers, it is a list of the batch flag followed by the messages, every
+// message is a list of its sequence number, the sequence number of the
+// previous message sent to the client and the data.
+func (t *topic) sequencedPayload(messages []pushMessage) interface{} {
+	payload := make([]interface{}, len(messages)+1)
+	payload[0] = t.options.Batch
+	for i, m := range messages {
+		payload[i+1] = []interface{}{m.seq, m.prev, m.data}
+	}
+	return payload
+}
+
+// wrap the data pushed to the topic with its name if the subscribed topic
+// is a pattern
+func (t *topic) wrap(topic string, data interface{}) interface{} {
+	if t.pattern {
+		return []interface{}{topic, data}
+	}
+	return data
+}
+
+// payload returns the data pushed to the client
+func (t *topic) payload(messages []pushMessage) interface{} {
+	if !t.options.Batch {
+		return messages[0].data
+	}
+	data := make([]interface{}, len(messages))
+	for i, m := range messages {
+		data[i] = m.data
+	}
+	return data
+}
+
+// remove the subscriber if cond is nil or cond returns true, it returns
+// the queued messages, or false if the subscriber has been removed.
+func (t *topic) remove(
+	s *subscriber, cond func(s *subscriber) bool) (queue []pushMessage, ok bool) {
+	t.Lock()
+	defer t.Unlock()
+	if t.subscribers[s.id] != s || (cond != nil && !cond(s)) {
+		return nil, false
+	}
+	delete(t.subscribers, s.id)
+	s.stopTimer()
+	queue = append(append(s.unacked, s.queue...), s.blocked...)
+	s.unacked = nil
+	s.queue = nil
+	s.blocked = nil
+	s.dropped += uint64(len(queue))
+	s.notify()
+	return queue, true
+}
+
+// takeNative returns the messages to send over native push, sending is
+// false if there is nothing to send.
+func (t *topic) takeNative(
+	s *subscriber) (messages []pushMessage, reverse *reverseTransport) {
+	t.Lock()
+	reverse = s.native
+	if reverse != nil && t.subscribers[s.id] == s {
+		messages = t.take(s)
+	}
+	if messages == nil {
+		s.sending = false
+	}
+	t.Unlock()
+	return
+}
+
+func (t *topic) stopSending(s *subscriber) {
 	t.Lock()
-	t.messages[id] = message
+	s.sending = false
 	t.Unlock()
 }
 
-func (t *topic) remove(id string) {
+// delivered counts the messages delivered to the subscriber natively
+func (t *topic) delivered(s *subscriber, n int) {
 	t.Lock()
-	delete(t.messages, id)
+	s.delivered += uint64(n)
+	s.seen(nil, nil)
 	t.Unlock()
 }
 
+// info must be called with the lock held
+func (t *topic) info(s *subscriber) SubscriberInfo {
+	return SubscriberInfo{
+		Topic:      t.name,
+		ID:         s.id,
+		RemoteAddr: s.remoteAddr,
+		Header:     s.header,
+		Identity:   s.identity,
+		Metadata:   s.metadata,
+		Native:     s.native != nil,
+		Subscribed: s.subscribed,
+		LastSeen:   s.lastSeen,
+	}
+}
+
+func (t *topic) subscriberInfo(s *subscriber) SubscriberInfo {
+	t.RLock()
+	defer t.RUnlock()
+	return t.info(s)
+}
+
+func (t *topic) presence() (result []SubscriberInfo) {
+	t.RLock()
+	result = make([]SubscriberInfo, 0, len(t.subscribers))
+	for _, s := range t.subscribers {
+		result = append(result, t.info(s))
+	}
+	t.RUnlock()
+	return
+}
+
+func (t *topic) stats() (result []QueueStats) {
+	t.RLock()
+	result = make([]QueueStats, 0, len(t.subscribers))
+	for id, s := range t.subscribers {
+		result = append(result, QueueStats{
+			ID:        id,
+			Length:    len(s.queue),
+			Blocked:   len(s.blocked),
+			Unacked:   len(s.unacked),
+			Delivered: s.delivered,
+			Dropped:   s.dropped,
+		})
+	}
+	t.RUnlock()
+	return
+}
+
 func (t *topic) idlist() (result []string) {
 	t.RLock()
-	result = make([]string, len(t.messages))
-	i := 0
-	for id := range t.messages {
-		result[i] = id
-		i++
+	result = make([]string, 0, len(t.subscribers))
+	for id := range t.subscribers {
+		result = append(result, id)
 	}
 	t.RUnlock()
 	return
 }
 
+func (t *topic) isEmpty() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return len(t.subscribers) == 0
+}
+
 func (t *topic) exist(id string) (exist bool) {
 	t.RLock()
-	_, exist = t.messages[id]
+	_, exist = t.subscribers[id]
 	t.RUnlock()
 	return
 }
@@ -0,0 +1,180 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/topic_test.go                                      *
+ *                                                        *
+ * hprose push topic test for Go.                         *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestTopic(options TopicOptions) (*baseService, *topic) {
+	service := NewHTTPService()
+	service.Publish("news", time.Second, time.Second, options)
+	return &service.baseService, service.resolveTopic("news")
+}
+
+func TestTopic_OverflowBlockOrder(t *testing.T) {
+	service, topic := newTestTopic(TopicOptions{})
+	s, _, _ := topic.beginPoll("c1", true, 0, nil, nil)
+	for i := 0; i < 50; i++ {
+		service.Push("news", i)
+	}
+	stats := service.QueueStats("news")
+	if stats[0].Length != 1 || stats[0].Blocked != 49 {
+		t.Error(stats)
+	}
+	var lastSeq int64
+	for i := 0; i < 50; i++ {
+		messages, _ := topic.poll(s, true)
+		if len(messages) != 1 {
+			t.Fatal(i, messages)
+		}
+		m := messages[0]
+		if m.data != i || m.prev != lastSeq {
+			t.Error(i, m.data, m.prev, lastSeq)
+		}
+		lastSeq = m.seq
+	}
+	if messages, _ := topic.poll(s, true); messages != nil {
+		t.Error(messages)
+	}
+}
+
+func TestTopic_RemovePatternTopic(t *testing.T) {
+	service := NewHTTPService()
+	service.Publish("news.*", time.Second, time.Second)
+	tp := service.resolveTopic("news.sports")
+	if tp == nil || !tp.dynamic {
+		t.Fatal(tp)
+	}
+	var s *subscriber
+	tp = service.attach(tp, func(found *topic) {
+		s, _, _ = found.beginPoll("c1", true, 0, nil, nil)
+	})
+	tp.endPoll(s)
+	service.offline(tp, s)
+	service.topicLock.RLock()
+	_, ok := service.topics["news.sports"]
+	matched := service.topicTree.match("news.sports")
+	service.topicLock.RUnlock()
+	if ok || len(matched) != 0 || len(service.topicTree.children) != 0 {
+		t.Error(ok, matched)
+	}
+	if service.resolveTopic("news.sports") == tp {
+		t.Error("the removed topic is resolved")
+	}
+}
+
+func seqList(messages []pushMessage) (seqs [][2]int64) {
+	for _, m := range messages {
+		seqs = append(seqs, [2]int64{m.seq, m.prev})
+	}
+	return
+}
+
+func TestTopic_ResumeRetained(t *testing.T) {
+	service, topic := newTestTopic(TopicOptions{QueueSize: 10, Retain: 3, Batch: true})
+	for i := 1; i <= 5; i++ {
+		service.Push("news", i)
+	}
+	// the client is restarted after it received the message 1, the
+	// messages 3 to 5 are retained, and the message 2 is lost
+	s, created, _ := topic.beginPoll("c1", true, 1, nil, nil)
+	if !created {
+		t.Fatal("the subscriber is not created")
+	}
+	messages, _ := topic.poll(s, true)
+	expected := [][2]int64{{3, 2}, {4, 3}, {5, 4}}
+	if seqs := seqList(messages); !reflect.DeepEqual(seqs, expected) {
+		t.Error(seqs)
+	}
+}
+
+func TestTopic_ResumeUnacked(t *testing.T) {
+	service, topic := newTestTopic(TopicOptions{QueueSize: 10, Batch: true})
+	s, _, _ := topic.beginPoll("c1", true, 0, nil, nil)
+	for i := 1; i <= 3; i++ {
+		service.Push("news", i)
+	}
+	if messages, _ := topic.poll(s, true); len(messages) != 3 {
+		t.Fatal(messages)
+	}
+	topic.endPoll(s)
+	// the client reconnects after it received the message 1 only
+	s, created, acked := topic.beginPoll("c1", true, 1, nil, nil)
+	if created || len(acked) != 1 || acked[0].seq != 1 {
+		t.Fatal(created, acked)
+	}
+	messages, _ := topic.poll(s, true)
+	expected := [][2]int64{{2, 1}, {3, 2}}
+	if seqs := seqList(messages); !reflect.DeepEqual(seqs, expected) {
+		t.Error(seqs)
+	}
+}
+
+type testPushGapEvent struct {
+	gaps [][2]int64
+}
+
+func (e *testPushGapEvent) OnPushGap(
+	topic string, id string, lastSeq int64, seq int64) {
+	e.gaps = append(e.gaps, [2]int64{lastSeq, seq})
+}
+
+func TestTopic_OverflowPushGap(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		received []interface{}
+		gaps     [][2]int64
+	}{
+		// the messages 2 and 3 are dropped when they are pushed
+		{OverflowDropNewest, []interface{}{1, 4}, [][2]int64{{1, 4}}},
+		// the messages 1 and 2 are dropped from the queue
+		{OverflowDropOldest, []interface{}{3, 4}, [][2]int64{{0, 3}}},
+	}
+	for _, test := range tests {
+		service, topic := newTestTopic(TopicOptions{Overflow: test.overflow})
+		s, _, _ := topic.beginPoll("c1", true, 0, nil, nil)
+		for i := 1; i <= 3; i++ {
+			service.Push("news", i)
+		}
+		messages, _ := topic.poll(s, true)
+		service.Push("news", 4)
+		next, _ := topic.poll(s, true)
+		messages = append(messages, next...)
+		client := NewInProcClient("inproc://news")
+		event := new(testPushGapEvent)
+		client.SetEvent(event)
+		var received []interface{}
+		ct := &clientTopic{callbacks: []Callback{
+			func(results []reflect.Value, err error) {
+				received = append(received, results[0].Interface())
+			},
+		}}
+		client.deliver("news", "c1", ct, topic.sequencedPayload(messages))
+		if !reflect.DeepEqual(received, test.received) {
+			t.Error(test.overflow, received)
+		}
+		if !reflect.DeepEqual(event.gaps, test.gaps) {
+			t.Error(test.overflow, event.gaps)
+		}
+	}
+}
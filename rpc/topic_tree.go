@@ -0,0 +1,137 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/topic_tree.go                                      *
+ *                                                        *
+ * hprose push topic tree for Go.                         *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import "strings"
+
+// The push topic names are hierarchical, the levels are separated by dots.
+// A topic pattern matches the topic names level by level, "*" matches
+// exactly one level, and "#" matches zero or more levels, for example,
+// "orders.eu.*" matches "orders.eu.fr", and "orders.#" matches "orders",
+// "orders.eu" and "orders.eu.fr".
+
+func splitTopic(name string) []string {
+	return strings.Split(name, ".")
+}
+
+// isTopicPattern returns true if the name contains "*" or "#" levels
+func isTopicPattern(name string) bool {
+	for _, level := range splitTopic(name) {
+		if level == "*" || level == "#" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchLevels(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(name); i++ {
+			if matchLevels(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(name) > 0 && matchLevels(pattern[1:], name[1:])
+	}
+	return len(name) > 0 && pattern[0] == name[0] &&
+		matchLevels(pattern[1:], name[1:])
+}
+
+// matchTopic returns true if the topic name matches the pattern, the
+// wildcards in name are matched as the plain levels.
+func matchTopic(pattern string, name string) bool {
+	return matchLevels(splitTopic(pattern), splitTopic(name))
+}
+
+// topicTree indexes the subscribed topics by levels, so the topics matching
+// a pushed topic name are found without comparing with all of them.
+type topicTree struct {
+	children map[string]*topicTree
+	topic    *topic
+}
+
+func newTopicTree() *topicTree {
+	return &topicTree{children: make(map[string]*topicTree)}
+}
+
+func (tree *topicTree) put(name string, t *topic) {
+	node := tree
+	for _, level := range splitTopic(name) {
+		child := node.children[level]
+		if child == nil {
+			child = newTopicTree()
+			node.children[level] = child
+		}
+		node = child
+	}
+	node.topic = t
+}
+
+// remove the topic of the name, and prune the empty nodes
+func (tree *topicTree) remove(name string) {
+	tree.prune(splitTopic(name))
+}
+
+// prune returns true if the node is empty then
+func (tree *topicTree) prune(levels []string) bool {
+	if len(levels) == 0 {
+		tree.topic = nil
+	} else if child := tree.children[levels[0]]; child != nil && child.prune(levels[1:]) {
+		delete(tree.children, levels[0])
+	}
+	return tree.topic == nil && len(tree.children) == 0
+}
+
+// match returns the topics whose names or patterns match the topic name
+func (tree *topicTree) match(name string) (topics []*topic) {
+	found := make(map[*topic]bool)
+	tree.collect(splitTopic(name), found)
+	for t := range found {
+		topics = append(topics, t)
+	}
+	return
+}
+
+func (tree *topicTree) collect(levels []string, found map[*topic]bool) {
+	if len(levels) == 0 {
+		if tree.topic != nil {
+			found[tree.topic] = true
+		}
+	} else {
+		if child := tree.children[levels[0]]; child != nil {
+			child.collect(levels[1:], found)
+		}
+		if child := tree.children["*"]; child != nil {
+			child.collect(levels[1:], found)
+		}
+	}
+	if child := tree.children["#"]; child != nil {
+		// "#" matches the rest levels from zero to all of them
+		for i := 0; i <= len(levels); i++ {
+			child.collect(levels[i:], found)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/topic_tree_test.go                                 *
+ *                                                        *
+ * hprose push topic tree test for Go.                    *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"news", "news", true},
+		{"news", "news.sports", false},
+		{"news.*", "news.sports", true},
+		{"news.*", "news", false},
+		{"news.*", "news.sports.football", false},
+		{"news.*.football", "news.sports.football", true},
+		{"news.#", "news", true},
+		{"news.#", "news.sports", true},
+		{"news.#", "news.sports.football", true},
+		{"news.#", "weather", false},
+		{"#", "news.sports", true},
+		{"#.football", "news.sports.football", true},
+		{"#.football", "news.sports.tennis", false},
+		{"*.*", "news", false},
+		{"*.#.football", "news.football", true},
+	}
+	for _, test := range tests {
+		if matchTopic(test.pattern, test.name) != test.match {
+			t.Errorf("matchTopic(%q, %q) should be %v",
+				test.pattern, test.name, test.match)
+		}
+	}
+}
+
+func TestTopicTree_Match(t *testing.T) {
+	patterns := []string{
+		"news", "news.*", "news.#", "news.*.football", "#", "#.football",
+		"*.sports", "weather.*", "*.#.football",
+	}
+	names := []string{
+		"news", "news.sports", "news.sports.football", "weather",
+		"weather.today", "sports.football",
+	}
+	tree := newTopicTree()
+	topics := make(map[*topic]string, len(patterns))
+	for _, pattern := range patterns {
+		tp := new(topic)
+		topics[tp] = pattern
+		tree.put(pattern, tp)
+	}
+	for _, name := range names {
+		var matched, expected []string
+		for _, tp := range tree.match(name) {
+			matched = append(matched, topics[tp])
+		}
+		for _, pattern := range patterns {
+			if matchTopic(pattern, name) {
+				expected = append(expected, pattern)
+			}
+		}
+		sort.Strings(matched)
+		sort.Strings(expected)
+		if len(matched) != len(expected) {
+			t.Errorf("%q matches %v, expected %v", name, matched, expected)
+			continue
+		}
+		for i := range matched {
+			if matched[i] != expected[i] {
+				t.Errorf("%q matches %v, expected %v", name, matched, expected)
+				break
+			}
+		}
+	}
+	tree.remove("news.#")
+	for _, tp := range tree.match("news.sports") {
+		if topics[tp] == "news.#" {
+			t.Error("news.# is not removed")
+		}
+	}
+}
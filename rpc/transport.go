@@ -0,0 +1,83 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/transport.go                                       *
+ *                                                        *
+ * hprose transport for Go.                               *
+ *                                                        *
+ * LastModified: Oct 17, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+// Transport sends the hprose requests of a TransportClient, it is the
+// easy way to add a custom scheme to NewClient by RegisterTransport.
+//
+// The requests have passed through the filters and the filter handlers
+// of the client, and the responses are passed back through them, so the
+// transport only needs to deliver the bytes.
+type Transport interface {
+	// SendAndReceive sends data to the service address context.URI and
+	// returns the response. It is called concurrently.
+	//
+	// It must return ErrTimeout if the response is not received in
+	// context.Timeout, and context.Context().Err() when context.Context()
+	// is done before that. For the oneway requests (context.Oneway), the
+	// response is ignored, it may return as soon as data is sent.
+	//
+	// If it returns an error, the client retries the request with the
+	// next service address when context.Failswitch is true, and retries
+	// it by the retry settings when context.Idempotent is true.
+	SendAndReceive(data []byte, context *ClientContext) ([]byte, error)
+	// Close is called when the client is closed, the pending SendAndReceive
+	// calls must return, and the later calls must return an error.
+	Close()
+}
+
+// TransportClient is the hprose client which sends the requests by a
+// Transport
+type TransportClient struct {
+	baseClient
+	Transport Transport
+}
+
+// NewTransportClient is the constructor of TransportClient
+func NewTransportClient(
+	transport Transport, uri ...string) (client *TransportClient) {
+	client = new(TransportClient)
+	client.initBaseClient()
+	client.Transport = transport
+	client.SendAndReceive = transport.SendAndReceive
+	client.SetURIList(uri)
+	return
+}
+
+// SetURIList set a list of server addresses
+func (client *TransportClient) SetURIList(uriList []string) {
+	checkAddresses(uriList, nil)
+	client.baseClient.SetURIList(uriList)
+}
+
+// Close the client
+func (client *TransportClient) Close() {
+	client.baseClient.Close()
+	client.Transport.Close()
+}
+
+// RegisterTransport registers a custom scheme, NewClient creates a
+// TransportClient for the addresses of the scheme, and the transport
+// of it is created by newTransport.
+func RegisterTransport(scheme string, newTransport func() Transport) {
+	RegisterClientFactory(scheme, func(uri ...string) Client {
+		return NewTransportClient(newTransport(), uri...)
+	})
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * reflect types for Go.                                  *
  *                                                        *
- * LastModified: Sep 16, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"net"
 	"net/http"
 	"reflect"
@@ -29,9 +30,12 @@ import (
 )
 
 var stringType = reflect.TypeOf("")
+var stringSliceType = reflect.TypeOf([]string(nil))
+var boolType = reflect.TypeOf(false)
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
 var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
 var contextType = reflect.TypeOf((*Context)(nil)).Elem()
+var goContextType = reflect.TypeOf((*gocontext.Context)(nil)).Elem()
 var serviceContextType = reflect.TypeOf((*ServiceContext)(nil)).Elem()
 var httpContextType = reflect.TypeOf((*HTTPContext)(nil))
 var httpRequestType = reflect.TypeOf((*http.Request)(nil))
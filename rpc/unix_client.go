@@ -12,7 +12,7 @@
  *                                                        *
  * hprose unx client for Go.                              *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -46,11 +46,11 @@ func newUnixClient(uri ...string) Client {
 // SetURIList set a list of server addresses
 func (client *UnixClient) SetURIList(uriList []string) {
 	checkAddresses(uriList, unixSchemes)
-	client.baseClient.SetURIList(uriList)
+	client.SocketClient.SetURIList(uriList)
 }
 
-func (client *UnixClient) createUnixConn() net.Conn {
-	u, err := url.Parse(client.uri)
+func (client *UnixClient) createUnixConn(uri string) net.Conn {
+	u, err := url.Parse(uri)
 	ifErrorPanic(err)
 	unixaddr, err := net.ResolveUnixAddr(u.Scheme, u.Path)
 	ifErrorPanic(err)
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose unix server for Go.                             *
  *                                                        *
- * LastModified: Oct 5, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"net"
 	"net/url"
 )
@@ -28,8 +29,11 @@ import (
 type UnixServer struct {
 	UnixService
 	starter
-	uri      string
-	listener *net.UnixListener
+	// Registrar announces the server address when Handle starts,
+	// and withdraws it when Close
+	Registrar Registrar
+	uri       string
+	listener  *net.UnixListener
 }
 
 // NewUnixServer is the constructor for UnixServer
@@ -57,6 +61,7 @@ func (server *UnixServer) Handle() (err error) {
 	if server.listener != nil {
 		return errServerIsAlreadyStarted
 	}
+	server.reopen()
 	u, err := url.Parse(server.uri)
 	if err != nil {
 		return err
@@ -68,6 +73,13 @@ func (server *UnixServer) Handle() (err error) {
 	if server.listener, err = net.ListenUnix(u.Scheme, addr); err != nil {
 		return err
 	}
+	if server.Registrar != nil {
+		if err = server.Registrar.Register(server.URI()); err != nil {
+			server.listener.Close()
+			server.listener = nil
+			return err
+		}
+	}
 	go server.ServeUnix(server.listener)
 	return nil
 }
@@ -75,8 +87,23 @@ func (server *UnixServer) Handle() (err error) {
 // Close the hprose unix server
 func (server *UnixServer) Close() {
 	if server.listener != nil {
+		if server.Registrar != nil {
+			if err := server.Registrar.Deregister(server.URI()); err != nil {
+				fireErrorEvent(server.Event, err, nil)
+			}
+		}
 		listener := server.listener
 		server.listener = nil
 		listener.Close()
 	}
 }
+
+// Shutdown the hprose unix server gracefully, it stops accepting new
+// connections, waits for the in-flight requests to finish until ctx is done,
+// releases the push subscribers, and then closes all connections.
+func (server *UnixServer) Shutdown(ctx gocontext.Context) error {
+	server.Close()
+	err := server.drain(ctx)
+	server.closeConns()
+	return err
+}
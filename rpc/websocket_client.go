@@ -12,7 +12,7 @@
  *                                                        *
  * hprose websocket client for Go.                        *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -33,17 +33,26 @@ type reqeust struct {
 	data []byte
 }
 
+type webSocketConn struct {
+	conn      *websocket.Conn
+	requests  chan reqeust
+	responses map[uint32]chan socketResponse
+	done      chan struct{}
+	// ready is closed when the #reverse request is responded
+	ready     chan struct{}
+	reverseID uint32
+}
+
 // WebSocketClient is hprose websocket client
 type WebSocketClient struct {
 	baseClient
+	reverseServiceHolder
 	limiter
 	http.Header
-	dialer    websocket.Dialer
-	conn      *websocket.Conn
-	nextid    uint32
-	requests  chan reqeust
-	responses map[uint32]chan socketResponse
-	closed    bool
+	dialer websocket.Dialer
+	conns  map[string]*webSocketConn
+	nextid uint32
+	closed bool
 }
 
 // NewWebSocketClient is the constructor of WebSocketClient
@@ -51,9 +60,12 @@ func NewWebSocketClient(uri ...string) (client *WebSocketClient) {
 	client = new(WebSocketClient)
 	client.initBaseClient()
 	client.initLimiter()
+	client.conns = make(map[string]*webSocketConn)
 	client.closed = false
 	client.SetURIList(uri)
 	client.SendAndReceive = client.sendAndReceive
+	client.nativePush = func() bool { return true }
+	client.setup = client.publishPush
 	return
 }
 
@@ -69,26 +81,40 @@ func (client *WebSocketClient) SetURIList(uriList []string) {
 	client.baseClient.SetURIList(uriList)
 }
 
-func (client *WebSocketClient) close(err error) {
-	client.cond.L.Lock()
-	if err != nil && client.responses != nil {
-		for _, response := range client.responses {
+// closeConn must be called with the lock held
+func (client *WebSocketClient) closeConn(uri string, wc *webSocketConn, err error) {
+	if client.conns[uri] == wc {
+		delete(client.conns, uri)
+	}
+	if wc.responses != nil {
+		for _, response := range wc.responses {
 			response <- socketResponse{nil, err}
+			client.unlimit()
+		}
+		wc.responses = nil
+		close(wc.done)
+		wc.conn.Close()
+		if !client.closed {
+			go client.resubscribe()
 		}
 	}
-	client.responses = nil
-	if client.conn != nil {
-		client.conn.Close()
-		client.conn = nil
-	}
-	client.reset()
+}
+
+func (client *WebSocketClient) close(uri string, wc *webSocketConn, err error) {
+	client.cond.L.Lock()
+	client.closeConn(uri, wc, err)
 	client.cond.L.Unlock()
 }
 
 // Close the client
 func (client *WebSocketClient) Close() {
+	client.baseClient.Close()
+	client.cond.L.Lock()
 	client.closed = true
-	client.close(errClientIsAlreadyClosed)
+	for uri, wc := range client.conns {
+		client.closeConn(uri, wc, errClientIsAlreadyClosed)
+	}
+	client.cond.L.Unlock()
 }
 
 // TLSClientConfig returns the tls.Config in hprose client
@@ -101,84 +127,164 @@ func (client *WebSocketClient) SetTLSClientConfig(config *tls.Config) {
 	client.dialer.TLSClientConfig = config
 }
 
-func (client *WebSocketClient) sendLoop() {
-	conn := client.conn
-	for request := range client.requests {
-		err := conn.WriteMessage(websocket.BinaryMessage, request.data)
-		if err != nil {
-			client.close(err)
-			break
+func (client *WebSocketClient) sendLoop(uri string, wc *webSocketConn) {
+	for {
+		select {
+		case request := <-wc.requests:
+			err := wc.conn.WriteMessage(websocket.BinaryMessage, request.data)
+			if err != nil {
+				client.close(uri, wc, err)
+				return
+			}
+		case <-wc.done:
+			return
 		}
 	}
-	client.requests = nil
 }
 
-func (client *WebSocketClient) recvLoop() {
-	conn := client.conn
+func (client *WebSocketClient) recvLoop(uri string, wc *webSocketConn) {
 	for {
-		msgType, data, err := conn.ReadMessage()
+		msgType, data, err := wc.conn.ReadMessage()
 		if err != nil {
-			client.close(err)
+			client.close(uri, wc, err)
 			break
 		}
 		if msgType == websocket.BinaryMessage {
 			id := toUint32(data)
+			if isReverseID(id) {
+				go client.reply(wc, data)
+				continue
+			}
+			if wc.reverseID != 0 && id == wc.reverseID {
+				wc.reverseID = 0
+				close(wc.ready)
+				continue
+			}
 			client.cond.L.Lock()
-			response := client.responses[id]
+			response := wc.responses[id]
 			if response != nil {
 				response <- socketResponse{data[4:], nil}
-				delete(client.responses, id)
+				delete(wc.responses, id)
+				client.unlimit()
 			}
-			client.unlimit()
 			client.cond.L.Unlock()
 		}
 	}
-	close(client.requests)
 }
 
-func (client *WebSocketClient) getConn(uri string) (err error) {
-	if client.conn == nil {
-		client.conn, _, err = client.dialer.Dial(uri, client.Header)
+// reply the request sent by the server
+func (client *WebSocketClient) reply(wc *webSocketConn, request []byte) {
+	response := client.handleReverse(request[4:])
+	buf := make([]byte, len(response)+4)
+	copy(buf, request[:4])
+	copy(buf[4:], response)
+	select {
+	case wc.requests <- reqeust{toUint32(buf), buf}:
+	case <-wc.done:
+	}
+}
+
+// getConn must be called with the lock held
+func (client *WebSocketClient) getConn(uri string) (*webSocketConn, error) {
+	wc := client.conns[uri]
+	if wc == nil {
+		conn, _, err := client.dialer.Dial(uri, client.Header)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		count := client.MaxConcurrentRequests
-		client.requests = make(chan reqeust, count)
-		client.responses = make(map[uint32]chan socketResponse, count)
-		go client.sendLoop()
-		go client.recvLoop()
+		wc = &webSocketConn{
+			conn:      conn,
+			requests:  make(chan reqeust, count),
+			responses: make(map[uint32]chan socketResponse, count),
+			done:      make(chan struct{}),
+			ready:     make(chan struct{}),
+		}
+		client.negotiate(wc)
+		client.conns[uri] = wc
+		go client.sendLoop(uri, wc)
+		go client.recvLoop(uri, wc)
 	}
-	return nil
+	return wc, nil
+}
+
+// negotiate sends the #reverse request if the ReverseService is created, the
+// requests of the client wait for its response, because the server handles
+// them concurrently.
+func (client *WebSocketClient) negotiate(wc *webSocketConn) {
+	var request []byte
+	if client.isCreated() {
+		request = client.reverseRequest()
+	}
+	if request == nil || cap(wc.requests) == 0 {
+		close(wc.ready)
+		return
+	}
+	wc.reverseID = atomic.AddUint32(&client.nextid, 1) &^ reverseFlag
+	buf := make([]byte, len(request)+4)
+	fromUint32(buf, wc.reverseID)
+	copy(buf[4:], request)
+	wc.requests <- reqeust{wc.reverseID, buf}
+}
+
+func (client *WebSocketClient) cancel(wc *webSocketConn, id uint32) {
+	client.cond.L.Lock()
+	if _, ok := wc.responses[id]; ok {
+		delete(wc.responses, id)
+		client.unlimit()
+	}
+	client.cond.L.Unlock()
 }
 
 func (client *WebSocketClient) sendAndReceive(
 	data []byte, context *ClientContext) ([]byte, error) {
-	id := atomic.AddUint32(&client.nextid, 1)
+	id := atomic.AddUint32(&client.nextid, 1) &^ reverseFlag
 	buf := make([]byte, len(data)+4)
 	fromUint32(buf, id)
 	copy(buf[4:], data)
-	response := make(chan socketResponse)
+	response := make(chan socketResponse, 1)
+	ctx := context.Context()
 	client.cond.L.Lock()
-	client.limit()
-	if client.closed {
+	if err := client.limit(ctx); err != nil {
 		client.cond.L.Unlock()
-		return nil, errClientIsAlreadyClosed
+		return nil, err
+	}
+	var wc *webSocketConn
+	err := errClientIsAlreadyClosed
+	if !client.closed {
+		wc, err = client.getConn(context.URI)
 	}
-	if err := client.getConn(client.uri); err != nil {
+	if err != nil {
+		client.unlimit()
 		client.cond.L.Unlock()
 		return nil, err
 	}
-	client.responses[id] = response
+	wc.responses[id] = response
 	client.cond.L.Unlock()
-	client.requests <- reqeust{id, buf}
+	timer := time.NewTimer(context.Timeout)
+	defer timer.Stop()
+	select {
+	case <-wc.ready:
+		select {
+		case wc.requests <- reqeust{id, buf}:
+		case <-wc.done:
+		}
+	case <-wc.done:
+	case <-timer.C:
+		client.cancel(wc, id)
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		client.cancel(wc, id)
+		return nil, ctx.Err()
+	}
 	select {
 	case resp := <-response:
 		return resp.data, resp.err
-	case <-time.After(context.Timeout):
-		client.cond.L.Lock()
-		delete(client.responses, id)
-		client.unlimit()
-		client.cond.L.Unlock()
+	case <-timer.C:
+		client.cancel(wc, id)
 		return nil, ErrTimeout
+	case <-ctx.Done():
+		client.cancel(wc, id)
+		return nil, ctx.Err()
 	}
 }
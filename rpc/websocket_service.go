@@ -12,7 +12,7 @@
  *                                                        *
  * hprose websocket service for Go.                       *
  *                                                        *
- * LastModified: Oct 9, 2016                              *
+ * LastModified: Oct 17, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"net/http"
 	"reflect"
 	"strings"
@@ -33,6 +34,19 @@ import (
 type WebSocketContext struct {
 	HTTPContext
 	WebSocket *websocket.Conn
+	reverse   *reverseTransport
+}
+
+// Client returns a client which invokes the methods published by the
+// ReverseService of the remote client over this websocket connection. The
+// invocations fail if the remote client hasn't created the ReverseService
+// before the connection is opened.
+func (context *WebSocketContext) Client() Client {
+	return context.reverse.getClient()
+}
+
+func (context *WebSocketContext) getReverse() *reverseTransport {
+	return context.reverse
 }
 
 // WebSocketService is the hprose websocket service
@@ -40,6 +54,8 @@ type WebSocketService struct {
 	HTTPService
 	websocket.Upgrader
 	contextPool sync.Pool
+	conns       map[*websocket.Conn]struct{}
+	connsLock   sync.Mutex
 }
 
 func websocketFixArguments(args []reflect.Value, context ServiceContext) {
@@ -69,11 +85,17 @@ func websocketFixArguments(args []reflect.Value, context ServiceContext) {
 // NewWebSocketService is the constructor of WebSocketService
 func NewWebSocketService() (service *WebSocketService) {
 	service = new(WebSocketService)
-	service.initBaseHTTPService()
+	service.initWebSocketService()
+	return
+}
+
+func (service *WebSocketService) initWebSocketService() {
+	service.initHTTPService()
 	service.contextPool = sync.Pool{
 		New: func() interface{} { return new(WebSocketContext) },
 	}
 	service.FixArguments = websocketFixArguments
+	service.conns = make(map[*websocket.Conn]struct{})
 	service.CheckOrigin = func(request *http.Request) bool {
 		origin := request.Header.Get("origin")
 		if origin != "" && origin != "null" {
@@ -85,7 +107,6 @@ func NewWebSocketService() (service *WebSocketService) {
 		}
 		return true
 	}
-	return
 }
 
 func (service *WebSocketService) acquireContext() (context *WebSocketContext) {
@@ -114,34 +135,54 @@ func (service *WebSocketService) ServeHTTP(
 		return
 	}
 	defer conn.Close()
-
+	if !service.trackConn(conn) {
+		return
+	}
+	defer service.untrackConn(conn)
+	ctx, cancel := gocontext.WithCancel(request.Context())
+	defer cancel()
 	mutex := new(sync.Mutex)
+	scheme := "ws://"
+	if request.TLS != nil {
+		scheme = "wss://"
+	}
+	reverse := newReverseTransport(
+		scheme+request.RemoteAddr,
+		func(id uint32, data []byte) error {
+			return writeWebSocketMessage(conn, mutex, id, data)
+		})
+	defer reverse.fail(errConnIsClosed)
 	for {
 		msgType, data, err := conn.ReadMessage()
 		if err != nil {
 			break
 		}
-		if msgType == websocket.BinaryMessage {
-			go service.handle(data, mutex, response, request, conn)
+		if msgType == websocket.BinaryMessage && len(data) >= 4 {
+			if id := toUint32(data); isReverseID(id) && reverse.isEnabled() {
+				reverse.dispatch(id, data[4:])
+				continue
+			}
+			// the requests received after the shutdown starts are
+			// rejected, or the clients which keep sending hold the shutdown
+			closing := service.isClosing()
+			if !closing {
+				service.begin()
+			}
+			go service.handle(
+				ctx, data, mutex, response, request, conn, reverse, closing)
 		}
 	}
 }
 
-func (service *WebSocketService) handle(
-	data []byte,
-	mutex *sync.Mutex,
-	response http.ResponseWriter,
-	request *http.Request,
-	conn *websocket.Conn) {
-	context := service.acquireContext()
-	context.initHTTPContext(service, response, request)
-	context.WebSocket = conn
-	id := data[0:4]
-	data = service.Handle(data[4:], context)
+func writeWebSocketMessage(
+	conn *websocket.Conn, mutex *sync.Mutex, id uint32, data []byte) error {
+	var header [4]byte
+	fromUint32(header[:], id)
 	mutex.Lock()
-	writer, err := context.WebSocket.NextWriter(websocket.BinaryMessage)
+	defer mutex.Unlock()
+	writer, err := conn.NextWriter(websocket.BinaryMessage)
 	if err == nil {
-		_, err = writer.Write(id)
+		_, err = writer.Write(header[:])
 	}
 	if err == nil {
 		_, err = writer.Write(data)
@@ -149,9 +190,69 @@ func (service *WebSocketService) handle(
 	if err == nil {
 		err = writer.Close()
 	}
-	mutex.Unlock()
+	return err
+}
+
+func (service *WebSocketService) handle(
+	ctx gocontext.Context,
+	data []byte,
+	mutex *sync.Mutex,
+	response http.ResponseWriter,
+	request *http.Request,
+	conn *websocket.Conn,
+	reverse *reverseTransport,
+	closing bool) {
+	context := service.acquireContext()
+	context.initHTTPContext(service, response, request)
+	context.setContext(ctx)
+	context.WebSocket = conn
+	context.reverse = reverse
+	id := toUint32(data)
+	if closing {
+		data = service.endError(errServiceIsShuttingDown, context)
+	} else {
+		data = service.Handle(data[4:], context)
+	}
+	err := writeWebSocketMessage(conn, mutex, id, data)
 	if err != nil {
 		fireErrorEvent(service.Event, err, context)
 	}
 	service.releaseContext(context)
+	if !closing {
+		service.end()
+	}
+}
+
+// trackConn returns false if the service is shutting down
+func (service *WebSocketService) trackConn(conn *websocket.Conn) bool {
+	service.connsLock.Lock()
+	defer service.connsLock.Unlock()
+	if service.isClosing() {
+		return false
+	}
+	service.conns[conn] = struct{}{}
+	return true
+}
+
+func (service *WebSocketService) untrackConn(conn *websocket.Conn) {
+	service.connsLock.Lock()
+	delete(service.conns, conn)
+	service.connsLock.Unlock()
+}
+
+// Shutdown releases the push subscribers, waits for the in-flight requests
+// to finish until ctx is done, and then closes all websocket connections.
+//
+// The websocket connections are hijacked from the http server, so they are
+// not closed by http.Server.Shutdown, use ShutdownHTTPServer to shut down
+// both of them.
+func (service *WebSocketService) Shutdown(ctx gocontext.Context) error {
+	err := service.drain(ctx)
+	service.connsLock.Lock()
+	for conn := range service.conns {
+		conn.Close()
+		delete(service.conns, conn)
+	}
+	service.connsLock.Unlock()
+	return err
 }